@@ -1,19 +1,44 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/i-zaitsev/gitcat/pkg/files"
+	"github.com/i-zaitsev/gitcat/pkg/gitcache"
+	"github.com/i-zaitsev/gitcat/pkg/gitclone"
 	"github.com/i-zaitsev/gitcat/pkg/gitpath"
 	"github.com/i-zaitsev/gitcat/pkg/log"
+	"github.com/i-zaitsev/gitcat/pkg/ls"
 	"github.com/i-zaitsev/gitcat/pkg/output"
+	"golang.org/x/term"
+)
+
+// Sources of the files of local repositories selected with -from.
+const (
+	fromWorktree = "worktree"
+	fromIndex    = "index"
+	fromHead     = "head"
 )
 
 type Cli struct {
-	location     *gitpath.GitPath
+	targets      []*target
+	reposFile    string
+	split        bool
+	rev          string
+	from         string
+	lfs          files.LFS
+	symlinks     ls.Symlinks
+	shorthand    gitpath.Shorthand
 	localDir     string
 	outFile      string
 	dryRun       bool
@@ -25,7 +50,37 @@ type Cli struct {
 	excludePaths gitpath.Paths
 	minSize      gitpath.Size
 	maxSize      gitpath.Size
-	headLines    int
+	lines        files.Lines
+	sample       files.Sample
+	profile      string
+	configs      []string
+	flags        *flag.FlagSet
+	command      *command
+	args         []string
+	top          int
+	jsonOut      bool
+	cloneOpts    gitclone.Options
+	sparse       bool
+	useCache     bool
+	cacheDir     string
+	cacheMax     int
+	reuse        bool
+	force        bool
+	yes          bool
+	tokenFile    string
+	strictHosts  bool
+	timeout      time.Duration
+	confirmMu    sync.Mutex
+
+	// Classes of files marked in .gitattributes that are included.
+	keepGenerated     bool
+	keepVendored      bool
+	keepDocs          bool
+	keepExportIgnored bool
+
+	// Classes of files detected by heuristics that are excluded.
+	noGenerated bool
+	noVendor    bool
 }
 
 func NewCLI() *Cli {
@@ -35,83 +90,329 @@ func NewCLI() *Cli {
 	}
 }
 
-// Parse takes args without the program's name and parses the flags.
+// Parse takes args without the program's name, selects the subcommand
+// and parses its flags. Without a known subcommand name, "cat" is assumed.
 func (c *Cli) Parse(args []string) error {
-	fs := flag.NewFlagSet("gitcat", flag.ContinueOnError)
+	cmd, args := lookupCommand(args)
+	c.command = cmd
 
+	fs := flag.NewFlagSet("gitcat "+cmd.name, flag.ContinueOnError)
 	fs.Usage = c.usage(fs)
-
-	fs.BoolVar(&c.dryRun, "dryrun", false, "dry run mode - log actions without executing them")
 	fs.BoolVar(&c.debug, "debug", false, "enable debug logging")
-	fs.BoolVar(&c.tmpClone, "tmp", false, "clone into a temporary directory which is deleted after execution")
-	fs.StringVar(&c.outFile, "out", "", "output file (without extension, uses -fmt for extension)")
-	fs.StringVar(&c.localDir, "dir", "", "local directory to clone into (defaults to repo name)")
-	fs.Var(&c.outFmt, "fmt", "output format (text, jsonl, or md)")
-	fs.Var(&c.keepExt, "keep", "comma-separated list of file extensions to keep (default: none)")
-	fs.Var(&c.includePaths, "path", "comma-separated paths to include")
-	fs.Var(&c.excludePaths, "exclude", "comma-separated paths to exclude")
-	fs.Var(&c.minSize, "minsize", "minimum file size in KB (e.g., 100)")
-	fs.Var(&c.maxSize, "maxsize", "maximum file size in KB (e.g., 500)")
-	fs.IntVar(&c.headLines, "head", 0, "number of lines to read from each file (0 = all)")
+	fs.DurationVar(&c.timeout, "timeout", 0, "abort after the given duration, e.g. 5m (0 = no timeout)")
+	if cmd.flags != nil {
+		cmd.flags(c, fs)
+	}
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	c.flags = fs
+
+	remaining := fs.Args()
+	if cmd.maxArgs != unlimitedArgs && len(remaining) > cmd.maxArgs {
+		fs.Usage()
+		return fmt.Errorf("too many arguments")
+	}
+
+	if !cmd.repo {
+		c.args = remaining
+		c.setLog()
+		return nil
+	}
+
+	// A repository config file only applies when a single repository is given.
+	configRepo := ""
+	if len(remaining) == 1 && c.reposFile == "" {
+		configRepo = remaining[0]
+	}
+	if err := c.loadConfigs(fs, configRepo); err != nil {
+		return err
+	}
 
 	c.setLog()
+	for _, path := range c.configs {
+		log.Debug("loaded config file", "path", path)
+	}
 
-	remaining := fs.Args()
-	if len(remaining) == 0 {
+	locations := remaining
+	if c.reposFile != "" {
+		list, err := readRepoList(c.reposFile)
+		if err != nil {
+			return err
+		}
+		locations = append(locations, list...)
+	}
+	if len(locations) == 0 {
 		fs.Usage()
 		return fmt.Errorf("repository URL is required")
 	}
+	if c.split && len(locations) > 1 && c.outFile == "" {
+		return fmt.Errorf("-split requires -out to name the output files")
+	}
+	if c.top < 0 {
+		return fmt.Errorf("-top must not be negative")
+	}
 
-	if len(remaining) > 1 {
-		fs.Usage()
-		return fmt.Errorf("too many arguments")
+	if c.sample != (files.Sample{}) {
+		if c.lines.Head != 0 || c.lines.Tail != 0 {
+			return fmt.Errorf("-sample cannot be used together with -head or -tail")
+		}
+		c.lines.Head = c.sample.Head
+		c.lines.Tail = c.sample.Tail
 	}
 
-	if location, err := gitpath.Parse(remaining[0]); err != nil {
+	if paths, ranges, err := files.SplitPathRanges(c.includePaths); err != nil {
 		return err
 	} else {
-		c.location = location
+		c.includePaths = paths
+		c.lines.Paths = ranges
+	}
+
+	switch c.from {
+	case fromWorktree, fromIndex:
+	case fromHead:
+		if c.rev != "" {
+			return fmt.Errorf("-from head and -rev cannot be used together")
+		}
+		c.rev = "HEAD"
+	default:
+		return fmt.Errorf("invalid -from %q: must be one of worktree, index, head", c.from)
+	}
+	if c.from == fromIndex && c.rev != "" {
+		return fmt.Errorf("-from index and -rev cannot be used together")
 	}
 
-	if c.localDir == "" {
-		c.localDir = c.inferLocalDir(c.location.Path)
+	// Reading objects from a partial clone would fetch every blob separately.
+	if c.rev != "" && !isFlagSet(fs, "filter") {
+		c.cloneOpts.Filter = ""
+	}
+	// A shallow clone has no other commit than the tip of the ref.
+	if c.rev != "" && c.rev != "HEAD" && !isFlagSet(fs, "depth") {
+		c.cloneOpts.Depth = 0
+	}
+
+	// Clones check out Git LFS pointer files unless the objects are fetched.
+	c.cloneOpts.LFS = c.lfs == files.LFSFetch
+
+	c.cloneOpts.Auth.SSHKey = expandHome(c.cloneOpts.Auth.SSHKey)
+	c.cloneOpts.Auth.KnownHosts = expandHome(c.cloneOpts.Auth.KnownHosts)
+	c.cloneOpts.Auth.InsecureHostKeys = !c.strictHosts
+
+	switch {
+	case c.reuse && c.force:
+		return fmt.Errorf("-reuse and -force cannot be used together")
+	case c.reuse:
+		c.cloneOpts.Existing = gitclone.Reuse
+	case c.force:
+		c.cloneOpts.Existing = gitclone.Force
+		c.cloneOpts.Confirm = c.confirmReplace
 	}
 
+	targets, err := c.newTargets(locations)
+	if err != nil {
+		return err
+	}
+	c.targets = targets
 	return nil
 }
 
+// Run executes the parsed subcommand, bounded by -timeout when set.
+func (c *Cli) Run(ctx context.Context) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	return c.command.run(ctx, c)
+}
+
+// repoFlags registers the flags shared by commands that select files from a repository.
+func (c *Cli) repoFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.dryRun, "dryrun", false, "dry run mode - print the selected files of a local repo, or log actions without executing them")
+	fs.BoolVar(&c.tmpClone, "tmp", false, "clone into a temporary directory which is deleted after execution")
+	fs.StringVar(&c.localDir, "dir", "", "local directory to clone into (defaults to repo name; the parent directory with several repos)")
+	fs.StringVar(&c.reposFile, "repos", "", "file listing repositories to process, one per line")
+	fs.Var(&c.keepExt, "keep", "comma-separated list of file extensions to keep (default: none)")
+	fs.Var(&c.includePaths, "path", "comma-separated paths to include")
+	fs.Var(&c.excludePaths, "exclude", "comma-separated paths to exclude")
+	fs.Var(&c.minSize, "minsize", "minimum file size in KB (e.g., 100)")
+	fs.Var(&c.maxSize, "maxsize", "maximum file size in KB (e.g., 500)")
+	fs.StringVar(&c.profile, "profile", "", "named profile to select from the config files")
+	fs.IntVar(&c.cloneOpts.Depth, "depth", 1, "number of commits to clone (0 = full history)")
+	fs.StringVar(&c.cloneOpts.Filter, "filter", "blob:none", "partial clone filter (empty = download all objects)")
+	fs.BoolVar(&c.sparse, "sparse", true, "check out only the -path directories of remote repositories")
+	fs.StringVar(&c.cloneOpts.Ref, "ref", "", "branch, tag or full commit hash to check out (defaults to the remote's default branch)")
+	fs.Var(&c.cloneOpts.Submodules, "submodules", "submodules to check out and list: none, shallow or recursive (default: none for clones, those checked out for local repositories)")
+	fs.BoolVar(&c.keepGenerated, "include-generated", false, "include files marked linguist-generated in .gitattributes")
+	fs.BoolVar(&c.keepVendored, "include-vendored", false, "include files marked linguist-vendored in .gitattributes")
+	fs.BoolVar(&c.keepDocs, "include-documentation", false, "include files marked linguist-documentation in .gitattributes")
+	fs.BoolVar(&c.keepExportIgnored, "include-export-ignore", false, "include files marked export-ignore in .gitattributes")
+	fs.BoolVar(&c.noGenerated, "no-generated", false, "exclude files detected as generated: code generator output, lockfiles and minified files")
+	fs.BoolVar(&c.noVendor, "no-vendor", false, "exclude files under vendor/, third_party/ and node_modules/ directories")
+	fs.Var(&c.symlinks, "symlinks", "how to list symbolic links: record (the target as content), follow (within the repository) or skip")
+	fs.Var(&c.lfs, "lfs", "what to do with Git LFS pointer files: placeholder, skip or fetch")
+	fs.StringVar(&c.from, "from", fromWorktree, "where to read local repositories from: worktree, index (staged content) or head")
+	fs.StringVar(&c.rev, "rev", "", "read files from the git objects of this commit (e.g., HEAD) instead of the working tree")
+	fs.BoolVar(&c.reuse, "reuse", false, "reuse an existing -dir clone of the same repo: fetch and reset it to -ref")
+	fs.BoolVar(&c.force, "force", false, "delete an existing -dir and clone again, after confirmation")
+	fs.BoolVar(&c.yes, "yes", false, "do not ask for confirmation with -force")
+	fs.StringVar(&c.tokenFile, "token-file", "", "file with an HTTPS token, sent to every HTTPS host (defaults to $GITCAT_TOKEN, $GITHUB_TOKEN or $GITLAB_TOKEN)")
+	fs.StringVar(&c.cloneOpts.Auth.SSHKey, "ssh-key", "", "private key file for SSH clones (e.g., ~/.ssh/deploy_key)")
+	fs.StringVar(&c.cloneOpts.Auth.KnownHosts, "known-hosts", "", "known_hosts file for SSH clones")
+	fs.StringVar(&c.shorthand.Host, "host", gitpath.DefaultHost, "host of owner/repo shorthand references")
+	fs.StringVar(&c.shorthand.Protocol, "protocol", gitpath.HTTPS, "protocol of shorthand references (https or ssh)")
+	fs.BoolVar(&c.strictHosts, "strict-host-keys", true, "reject SSH hosts whose key is not in the known_hosts file")
+	fs.BoolVar(&c.useCache, "cache", false, "clone remote repositories from mirrors kept in the cache")
+	c.cacheFlags(fs)
+}
+
+// cacheFlags registers the flags locating and bounding the clone cache.
+func (c *Cli) cacheFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.cacheDir, "cache-dir", gitcache.DefaultDir(), "clone cache directory")
+	fs.IntVar(&c.cacheMax, "cache-max", 0, "maximum clone cache size in MB (0 = unlimited)")
+}
+
+// cache returns the clone cache configured by the flags.
+func (c *Cli) cache() *gitcache.Cache {
+	return gitcache.New(c.cacheDir, int64(c.cacheMax)*1024*1024)
+}
+
+// outputFlags registers the flags controlling the concatenated output.
+func (c *Cli) outputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.outFile, "out", "", "output file (without extension, uses -fmt for extension)")
+	fs.Var(&c.outFmt, "fmt", "output format (text, jsonl, or md)")
+	fs.BoolVar(&c.split, "split", false, "write one output per repository, named <out>-<repo>, instead of a combined one")
+	fs.IntVar(&c.lines.Head, "head", 0, "number of lines to read from the start of each file (0 = all)")
+	fs.IntVar(&c.lines.Tail, "tail", 0, "number of lines to read from the end of each file (0 = all)")
+	fs.Var(&c.lines.Range, "lines", "line range to read from each file (e.g., 100-250); use path:100-250 in -path for specific files")
+	fs.Var(&c.sample, "sample", "keep both ends of each file with an elision marker in between (e.g., head=40,tail=20)")
+}
+
+// loadConfigs applies the user config and, for local repositories,
+// the repo config on top of it. Flags given on the command line win.
+// The repo config may only set the options in repoConfigOptions.
+func (c *Cli) loadConfigs(fs *flag.FlagSet, location string) error {
+	paths := []string{userConfigPath()}
+	repoConfig := ""
+	if local, err := gitpath.FromDir(location); err == nil {
+		repoConfig = filepath.Join(local.Path, repoConfigName)
+		paths = append(paths, repoConfig)
+	}
+
+	var configs []*config
+	for _, path := range paths {
+		cfg, err := loadConfig(path)
+		if err != nil {
+			return err
+		}
+		if cfg == nil {
+			continue
+		}
+		if path == repoConfig {
+			if err := cfg.checkRepoOptions(); err != nil {
+				return err
+			}
+		}
+		configs = append(configs, cfg)
+		c.configs = append(c.configs, cfg.path)
+	}
+
+	values, err := mergeConfigs(c.profile, configs...)
+	if err != nil {
+		return err
+	}
+	return applyConfig(fs, values)
+}
+
 func (c *Cli) usage(fs *flag.FlagSet) func() {
 	return func() {
 		old := fs.Output()
 		var b strings.Builder
 		fs.SetOutput(&b)
-		b.WriteString("gitcat - concatenates a git repo into a single file\n\n")
-		b.WriteString("usage: gitcat [options] <repository-url>\n\n")
-		b.WriteString("options:\n")
+		cmd := c.command
+		b.WriteString("gitcat " + cmd.name + " - " + cmd.summary + "\n\n")
+		b.WriteString("usage: gitcat " + cmd.name + " [options]")
+		if cmd.synopsis != "" {
+			b.WriteString(" " + cmd.synopsis)
+		}
+		b.WriteString("\n")
+		if cmd.name == defaultCommand {
+			b.WriteString("       gitcat [options] " + cmd.synopsis + "\n")
+			b.WriteString("\ncommands:\n")
+			for _, other := range commands {
+				_, _ = fmt.Fprintf(&b, "  %-8s %s\n", other.name, other.summary)
+			}
+			b.WriteString("\nrun \"gitcat help <command>\" for the options of a command\n")
+		}
+		b.WriteString("\noptions:\n")
 		fs.PrintDefaults()
-		b.WriteString("\nexamples:\n")
-		b.WriteString("  gitcat git@github.com:user/repo.git\n")
-		b.WriteString("  gitcat https://github.com/user/repo.git\n")
-		b.WriteString("  gitcat -dryrun -dir ./myrepo git@github.com:user/repo.git\n")
-		b.WriteString("  gitcat -path pkg/files,cmd -exclude testdata https://github.com/user/repo.git\n")
-		b.WriteString("  gitcat -maxsize 500 -keep .go https://github.com/user/repo.git\n")
-		b.WriteString("  gitcat -head 50 https://github.com/user/repo.git\n")
+		if cmd.repo {
+			b.WriteString("\nconfig:\n")
+			b.WriteString("  defaults for any option are read from " + userConfigPath() + "\n")
+			b.WriteString("  and from " + repoConfigName + " in the root of a local repository, which may only\n")
+			b.WriteString("  set options selecting files and formatting the output;\n")
+			b.WriteString("  named profiles are declared under \"profiles\" and selected with -profile\n")
+		}
+		if len(cmd.examples) > 0 {
+			b.WriteString("\nexamples:\n")
+			for _, example := range cmd.examples {
+				b.WriteString("  " + example + "\n")
+			}
+		}
 		fs.SetOutput(old)
 		_, _ = fmt.Fprintln(fs.Output(), b.String())
 	}
 }
 
-func (c *Cli) inferLocalDir(repoURL string) string {
-	parts := strings.Split(repoURL, "/")
-	if len(parts) == 0 {
-		return "repo"
+// confirmReplace asks on the terminal whether an existing clone target may be deleted.
+// Without a terminal, only -yes allows the deletion.
+func (c *Cli) confirmReplace(ctx context.Context, dir string) bool {
+	if c.yes {
+		return true
+	}
+	c.confirmMu.Lock()
+	defer c.confirmMu.Unlock()
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		log.Warn("cannot ask for confirmation without a terminal, use -yes", "dir", dir)
+		return false
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "delete %s and clone again? [y/N] ", dir)
+	return readConfirmation(ctx, os.Stdin)
+}
+
+// readConfirmation reads a yes or no answer from r. A blocked read does not
+// hold up cancellation: once ctx is done, the answer is no.
+func readConfirmation(ctx context.Context, r io.Reader) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	answers := make(chan string, 1)
+	go func() {
+		answer, _ := bufio.NewReader(r).ReadString('\n')
+		answers <- answer
+	}()
+	select {
+	case <-ctx.Done():
+		return false
+	case answer := <-answers:
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+}
+
+// expandHome replaces a leading ~/ with the user's home directory,
+// since paths from config files are not expanded by the shell.
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
 	}
-	last := parts[len(parts)-1]
-	return strings.TrimSuffix(last, ".git")
+	return filepath.Join(home, rest)
 }
 
 func (c *Cli) setLog() {
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRevClone(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tests := []struct {
+		args       []string
+		wantDepth  int
+		wantFilter string
+	}{
+		{[]string{"https://github.com/user/repo.git"}, 1, "blob:none"},
+		{[]string{"-rev", "HEAD", "https://github.com/user/repo.git"}, 1, ""},
+		{[]string{"-from", "head", "https://github.com/user/repo.git"}, 1, ""},
+		{[]string{"-rev", "v1.2.0", "https://github.com/user/repo.git"}, 0, ""},
+		{[]string{"-rev", "v1.2.0", "-depth", "5", "-filter", "tree:0", "https://github.com/user/repo.git"}, 5, "tree:0"},
+	}
+	for _, tt := range tests {
+		c := NewCLI()
+		if err := c.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		opts := c.targets[0].cloneOpts
+		if opts.Depth != tt.wantDepth || opts.Filter != tt.wantFilter {
+			t.Errorf("Parse(%q): depth %d, filter %q, want %d, %q", tt.args, opts.Depth, opts.Filter, tt.wantDepth, tt.wantFilter)
+		}
+	}
+}
+
+func TestParseFromIndex(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	local := t.TempDir()
+
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{[]string{"-from", "index", local}, false},
+		{[]string{"-from", "index", "https://github.com/user/repo.git"}, true},
+		{[]string{"-from", "index", local, "git@github.com:user/repo.git"}, true},
+		{[]string{"-from", "head", "https://github.com/user/repo.git"}, false},
+		{[]string{"-from", "index", "-rev", "HEAD", local}, true},
+	}
+	for _, tt := range tests {
+		if err := NewCLI().Parse(tt.args); (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestKeptMarks(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{"-include-export-ignore", "-include-generated"}, []string{"linguist-generated", "export-ignore"}},
+		{
+			[]string{"-include-documentation", "-include-vendored", "-include-generated", "-include-export-ignore"},
+			[]string{"linguist-generated", "linguist-vendored", "linguist-documentation", "export-ignore"},
+		},
+	}
+	for _, tt := range tests {
+		c := NewCLI()
+		if err := c.Parse(append(tt.args, t.TempDir())); err != nil {
+			t.Fatal(err)
+		}
+		for range 10 {
+			if got := c.keptMarks(); !slices.Equal(got, tt.want) {
+				t.Fatalf("keptMarks() with %q = %q, want %q", tt.args, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestWritesTags(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{dir}, true},
+		{[]string{"cat", "-fmt", "md", dir}, true},
+		{[]string{"cat", "-fmt", "text", dir}, false},
+		{[]string{"ls", dir}, false},
+		{[]string{"stats", dir}, false},
+	}
+	for _, tt := range tests {
+		c := NewCLI()
+		if err := c.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if got := c.writesTags(); got != tt.want {
+			t.Errorf("writesTags() with %q = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestParseLocationRef(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tests := []struct {
+		args      []string
+		wantRef   string
+		wantPaths []string
+	}{
+		{[]string{"https://github.com/org/repo/tree/main"}, "main", nil},
+		{[]string{"https://github.com/org/repo/tree/release/2.3/pkg"}, "", nil},
+		{[]string{"-ref", "release/2.3", "https://github.com/org/repo/tree/release/2.3/pkg"}, "release/2.3", []string{"pkg"}},
+		{[]string{"-ref", "release", "https://github.com/org/repo/tree/release/2.3/pkg"}, "release", []string{"2.3/pkg"}},
+		{[]string{"-sparse", "-ref", "feature/x", "https://github.com/org/repo/tree/feature/x"}, "feature/x", nil},
+		{[]string{"org/repo@dev"}, "dev", nil},
+	}
+	for _, tt := range tests {
+		c := NewCLI()
+		if err := c.Parse(tt.args); err != nil {
+			t.Fatalf("Parse(%q): %v", tt.args, err)
+		}
+		target := c.targets[0]
+		if target.cloneOpts.Ref != tt.wantRef || !slices.Equal(target.paths, tt.wantPaths) {
+			t.Errorf("Parse(%q) ref, paths = %q, %q, want %q, %q", tt.args,
+				target.cloneOpts.Ref, target.paths, tt.wantRef, tt.wantPaths)
+		}
+	}
+}
+
+func TestParseSample(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	const repo = "https://github.com/user/repo.git"
+
+	tests := []struct {
+		args     []string
+		wantHead int
+		wantTail int
+		wantErr  bool
+	}{
+		{[]string{"-sample", "head=40,tail=20", repo}, 40, 20, false},
+		{[]string{"-head", "10", "-tail", "5", repo}, 10, 5, false},
+		{[]string{"-sample", "head=40,tail=20", "-head", "10", repo}, 0, 0, true},
+		{[]string{"-tail", "5", "-sample", "head=40,tail=20", repo}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		c := NewCLI()
+		err := c.Parse(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (c.lines.Head != tt.wantHead || c.lines.Tail != tt.wantTail) {
+			t.Errorf("Parse(%q) head, tail = %d, %d, want %d, %d", tt.args, c.lines.Head, c.lines.Tail, tt.wantHead, tt.wantTail)
+		}
+	}
+}
+
+func TestReadConfirmation(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"Yes\n", true},
+		{"  y  \n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+		{"yes please\n", false},
+	}
+	for _, tt := range tests {
+		if got := readConfirmation(context.Background(), strings.NewReader(tt.input)); got != tt.want {
+			t.Errorf("readConfirmation(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestReadConfirmationCanceled(t *testing.T) {
+	r, w := io.Pipe()
+	defer func() { _ = w.Close() }()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	done := make(chan bool)
+	go func() { done <- readConfirmation(ctx, r) }()
+	select {
+	case got := <-done:
+		if got {
+			t.Error("readConfirmation() = true after cancellation, want false")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("readConfirmation() blocked after cancellation")
+	}
+	if readConfirmation(ctx, strings.NewReader("y\n")) {
+		t.Error("readConfirmation() with a canceled context = true, want false")
+	}
+}
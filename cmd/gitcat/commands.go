@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/i-zaitsev/gitcat/pkg/log"
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+	"github.com/i-zaitsev/gitcat/pkg/output"
+	"github.com/i-zaitsev/gitcat/pkg/stats"
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = ""
+
+const defaultCommand = "cat"
+
+// unlimitedArgs is the maxArgs of commands that take any number of arguments.
+const unlimitedArgs = -1
+
+// command describes a gitcat subcommand with its flags and entry point.
+type command struct {
+	name     string
+	summary  string
+	synopsis string
+	examples []string
+	repo     bool // the first positional argument is a repository
+	maxArgs  int
+	flags    func(c *Cli, fs *flag.FlagSet)
+	run      func(ctx context.Context, c *Cli) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{
+			name:     "cat",
+			summary:  "concatenates a git repo into a single file",
+			synopsis: "<repository>...",
+			repo:     true,
+			maxArgs:  unlimitedArgs,
+			flags: func(c *Cli, fs *flag.FlagSet) {
+				c.repoFlags(fs)
+				c.outputFlags(fs)
+			},
+			run: runCat,
+			examples: []string{
+				"gitcat git@github.com:user/repo.git",
+				"gitcat https://github.com/user/repo.git",
+				"gitcat -dryrun -dir ./myrepo git@github.com:user/repo.git",
+				"gitcat -path pkg/files,cmd -exclude testdata https://github.com/user/repo.git",
+				"gitcat -maxsize 500 -keep .go https://github.com/user/repo.git",
+				"gitcat -head 50 https://github.com/user/repo.git",
+				"gitcat -profile review /path/to/local/repo",
+				"gitcat -sample head=40,tail=20 -path cmd/main.go:100-250,pkg https://github.com/user/repo.git",
+				"gitcat -path api gh:org/users gh:org/orders ../local/billing",
+				"gitcat -repos services.txt -split -out review",
+			},
+		},
+		{
+			name:     "ls",
+			summary:  "prints the filtered list of files",
+			synopsis: "<repository>...",
+			repo:     true,
+			maxArgs:  unlimitedArgs,
+			flags:    (*Cli).repoFlags,
+			run:      runLs,
+			examples: []string{
+				"gitcat ls -keep .go -exclude vendor /path/to/local/repo",
+			},
+		},
+		{
+			name:     "stats",
+			summary:  "prints size, line and token counts per extension, directory and language",
+			synopsis: "<repository>...",
+			repo:     true,
+			maxArgs:  unlimitedArgs,
+			flags: func(c *Cli, fs *flag.FlagSet) {
+				c.repoFlags(fs)
+				fs.IntVar(&c.top, "top", 10, "number of largest files to report")
+				fs.BoolVar(&c.jsonOut, "json", false, "print the report as JSON")
+			},
+			run: runStats,
+			examples: []string{
+				"gitcat stats https://github.com/user/repo.git",
+				"gitcat stats -json -top 20 -exclude vendor /path/to/local/repo",
+			},
+		},
+		{
+			name:     "tree",
+			summary:  "prints the filtered files as a directory tree",
+			synopsis: "<repository>...",
+			repo:     true,
+			maxArgs:  unlimitedArgs,
+			flags:    (*Cli).repoFlags,
+			run:      runTree,
+			examples: []string{
+				"gitcat tree -path pkg /path/to/local/repo",
+			},
+		},
+		{
+			name:     "unpack",
+			summary:  "recreates files from a jsonl or md output",
+			synopsis: "[output-file]",
+			maxArgs:  1,
+			flags: func(c *Cli, fs *flag.FlagSet) {
+				fs.StringVar(&c.localDir, "dir", ".", "directory to unpack files into")
+				fs.Var(&c.outFmt, "fmt", "input format (jsonl or md, defaults to the file extension)")
+			},
+			run: runUnpack,
+			examples: []string{
+				"gitcat unpack -dir ./restored repo.jsonl",
+				"gitcat cat /path/to/repo | gitcat unpack -dir ./restored",
+			},
+		},
+		{
+			name:     "cache",
+			summary:  "lists, prunes or clears the clone cache",
+			synopsis: "ls|prune|clear",
+			maxArgs:  1,
+			flags:    (*Cli).cacheFlags,
+			run:      runCache,
+			examples: []string{
+				"gitcat -cache -cache-max 2048 https://github.com/user/repo.git",
+				"gitcat cache ls",
+				"gitcat cache -cache-max 1024 prune",
+			},
+		},
+		{
+			name:    "version",
+			summary: "prints the gitcat version",
+			run:     runVersion,
+		},
+	}
+}
+
+// lookupCommand returns the subcommand named by the first argument and the
+// remaining arguments. "help <command>" maps to the command's -h flag.
+// Any other first argument selects the default command with all arguments,
+// as does a lone command name that is also an existing path, so that a
+// local directory named e.g. "ls" is concatenated.
+func lookupCommand(args []string) (*command, []string) {
+	if len(args) == 1 && (args[0] == "help" || findCommand(args[0]) != nil) {
+		if _, err := os.Stat(args[0]); err == nil {
+			return findCommand(defaultCommand), args
+		}
+	}
+	if len(args) > 0 && args[0] == "help" {
+		if len(args) > 1 {
+			if cmd := findCommand(args[1]); cmd != nil {
+				return cmd, []string{"-h"}
+			}
+		}
+		return findCommand(defaultCommand), []string{"-h"}
+	}
+	if len(args) > 0 {
+		if cmd := findCommand(args[0]); cmd != nil {
+			return cmd, args[1:]
+		}
+	}
+	return findCommand(defaultCommand), args
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// knownOption reports whether any subcommand defines the named flag.
+func knownOption(name string) bool {
+	for _, cmd := range commands {
+		if cmd.flags == nil {
+			continue
+		}
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		cmd.flags(NewCLI(), fs)
+		if fs.Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func runCat(ctx context.Context, c *Cli) error {
+	if c.split && len(c.targets) > 1 {
+		return runCatSplit(ctx, c)
+	}
+
+	repo, cleanup, err := c.selectFiles(ctx)
+	if err != nil || repo == nil {
+		return err
+	}
+	defer cleanup()
+
+	content, err := c.render(ctx, repo)
+	if err != nil {
+		return err
+	}
+	return writeOutput(ctx, content, c.outFile, c.outFmt)
+}
+
+// runCatSplit writes a separate output file for each repository.
+func runCatSplit(ctx context.Context, c *Cli) error {
+	repos, cleanup, err := c.selectRepos(ctx)
+	if err != nil || repos == nil {
+		return err
+	}
+	defer cleanup()
+
+	for i, repo := range repos {
+		content, err := c.render(ctx, repo)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.targets[i].name, err)
+		}
+		outFile := c.outFile + "-" + strings.ReplaceAll(c.targets[i].name, "/", "-")
+		if err := writeOutput(ctx, content, outFile, c.outFmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writesTags reports whether the output includes the tags of the files,
+// which only the JSONL and Markdown formats of the cat command do.
+func (c *Cli) writesTags() bool {
+	return c.command.name == "cat" && c.outFmt != output.FormatText
+}
+
+// render formats the selected files in the -fmt output format.
+func (c *Cli) render(ctx context.Context, repo *ls.RepoContent) (string, error) {
+	var (
+		content string
+		err     error
+	)
+	switch c.outFmt {
+	case output.FormatJSONL:
+		log.Info("writing output to FormatGrouped")
+		content, err = output.ToJSONL(ctx, repo, c.lines)
+	case output.FormatText:
+		log.Info("writing output to text")
+		content, err = output.ToText(ctx, repo, c.lines)
+	case output.FormatMarkdown:
+		log.Info("writing output to markdown")
+		content, err = output.ToMarkdown(ctx, repo, c.lines)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to generate %s output: %w", c.outFmt, err)
+	}
+	return content, nil
+}
+
+func runLs(ctx context.Context, c *Cli) error {
+	repo, cleanup, err := c.selectFiles(ctx)
+	if err != nil || repo == nil {
+		return err
+	}
+	defer cleanup()
+
+	for _, file := range repo.Files {
+		fmt.Println(file)
+	}
+	return nil
+}
+
+func runTree(ctx context.Context, c *Cli) error {
+	repo, cleanup, err := c.selectFiles(ctx)
+	if err != nil || repo == nil {
+		return err
+	}
+	defer cleanup()
+
+	fmt.Print(output.ToTree(repo))
+	return nil
+}
+
+func runStats(ctx context.Context, c *Cli) error {
+	repo, cleanup, err := c.selectFiles(ctx)
+	if err != nil || repo == nil {
+		return err
+	}
+	defer cleanup()
+
+	report, err := stats.Collect(ctx, repo, c.top)
+	if err != nil {
+		return err
+	}
+	if c.jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return report.WriteTable(os.Stdout)
+}
+
+func runUnpack(ctx context.Context, c *Cli) error {
+	var (
+		in   io.Reader = os.Stdin
+		name           = "stdin"
+	)
+	if len(c.args) > 0 && c.args[0] != "-" {
+		f, err := os.Open(c.args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open input: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		in, name = f, c.args[0]
+	}
+
+	format := c.outFmt
+	if !isFlagSet(c.flags, "fmt") && strings.HasSuffix(name, "."+output.FormatMarkdown) {
+		format = output.FormatMarkdown
+	}
+
+	var (
+		entries []output.Entry
+		err     error
+	)
+	switch format {
+	case output.FormatJSONL:
+		entries, err = output.ParseJSONL(bufio.NewReader(in))
+	case output.FormatMarkdown:
+		entries, err = output.ParseMarkdown(bufio.NewReader(in))
+	default:
+		return fmt.Errorf("cannot unpack %s output: file names are not recorded", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !filepath.IsLocal(entry.File) {
+			return fmt.Errorf("refusing to unpack %q: path escapes the target directory", entry.File)
+		}
+		target := filepath.Join(c.localDir, filepath.FromSlash(entry.File))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		_, err = f.WriteString(entry.Content)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		log.Debug("unpacked file", "file", target)
+	}
+
+	log.Info("unpacked files", "count", len(entries), "dir", c.localDir)
+	return nil
+}
+
+func runCache(ctx context.Context, c *Cli) error {
+	if len(c.args) == 0 {
+		c.flags.Usage()
+		return fmt.Errorf("cache action is required")
+	}
+
+	cache := c.cache()
+	switch action := c.args[0]; action {
+	case "ls":
+		entries, err := cache.List()
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "size\tlast used\turl")
+		for _, e := range entries {
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\n", e.Size, e.LastUsed.Format(time.DateTime), e.URL)
+		}
+		return tw.Flush()
+	case "prune":
+		if cache.MaxSize <= 0 {
+			return fmt.Errorf("cache prune requires -cache-max")
+		}
+		removed, err := cache.Prune()
+		log.Info("pruned cache", "removed", len(removed))
+		return err
+	case "clear":
+		return cache.Clear()
+	default:
+		return fmt.Errorf("unknown cache action %q: must be one of: ls, prune, clear", action)
+	}
+}
+
+func runVersion(context.Context, *Cli) error {
+	fmt.Println("gitcat", buildVersion())
+	return nil
+}
+
+// buildVersion returns the version set at build time, falling back
+// to the module version recorded by the go tool.
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestLookupCommand(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.Mkdir("stats", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		wantCmd  string
+		wantArgs []string
+	}{
+		{nil, "cat", nil},
+		{[]string{"repo"}, "cat", []string{"repo"}},
+		{[]string{"ls"}, "ls", []string{}},
+		{[]string{"ls", "repo"}, "ls", []string{"repo"}},
+		{[]string{"stats"}, "cat", []string{"stats"}},
+		{[]string{"stats", "repo"}, "stats", []string{"repo"}},
+		{[]string{"--", "ls"}, "cat", []string{"--", "ls"}},
+		{[]string{"help", "tree"}, "tree", []string{"-h"}},
+		{[]string{"help"}, "cat", []string{"-h"}},
+	}
+	for _, tt := range tests {
+		cmd, args := lookupCommand(tt.args)
+		if cmd.name != tt.wantCmd || !slices.Equal(args, tt.wantArgs) {
+			t.Errorf("lookupCommand(%q) = %s %q, want %s %q", tt.args, cmd.name, args, tt.wantCmd, tt.wantArgs)
+		}
+	}
+}
+
+func TestParseTop(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{[]string{"stats", "-top", "0", "."}, false},
+		{[]string{"stats", "-top", "5", "."}, false},
+		{[]string{"stats", "-top", "-1", "."}, true},
+	}
+	for _, tt := range tests {
+		if err := NewCLI().Parse(tt.args); (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+		}
+	}
+}
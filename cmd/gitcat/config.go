@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const repoConfigName = ".gitcat.yaml"
+
+// repoConfigOptions are the options a repository config file may set. A
+// repository is not trusted with options that write files, pick credentials
+// or relax host key checks, so it is limited to selecting files and
+// formatting the output.
+var repoConfigOptions = []string{
+	"keep", "path", "exclude", "minsize", "maxsize", "submodules", "symlinks", "from",
+	"include-generated", "include-vendored", "include-documentation", "include-export-ignore",
+	"no-generated", "no-vendor",
+	"fmt", "head", "tail", "lines", "sample", "top", "json",
+}
+
+// config holds flag defaults read from a config file.
+// Keys are flag names; profiles are named sets of flag values.
+type config struct {
+	path     string
+	Values   map[string]any            `yaml:",inline"`
+	Profiles map[string]map[string]any `yaml:"profiles"`
+}
+
+// userConfigPath returns the location of the per-user config file,
+// under $XDG_CONFIG_HOME or, when unset, ~/.config on every platform.
+func userConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "gitcat", "config.yaml")
+}
+
+// loadConfig reads a config file. A missing file yields a nil config.
+func loadConfig(path string) (*config, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg := config{path: path}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// checkRepoOptions reports an error if the config, read from a repository,
+// sets an option outside repoConfigOptions.
+func (cfg *config) checkRepoOptions() error {
+	check := func(values map[string]any) error {
+		for name := range values {
+			if !slices.Contains(repoConfigOptions, name) {
+				return fmt.Errorf("config %s: option %q cannot be set in a repository config file", cfg.path, name)
+			}
+		}
+		return nil
+	}
+	if err := check(cfg.Values); err != nil {
+		return err
+	}
+	for _, values := range cfg.Profiles {
+		if err := check(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeConfigs flattens the configs into a single set of flag values.
+// The defaults of all configs are merged first, later configs overriding
+// earlier ones, and the selected profile is applied on top of them.
+func mergeConfigs(profile string, configs ...*config) (map[string]any, error) {
+	merged := make(map[string]any)
+	for _, cfg := range configs {
+		if cfg == nil {
+			continue
+		}
+		for k, v := range cfg.Values {
+			merged[k] = v
+		}
+	}
+
+	found := profile == ""
+	for _, cfg := range configs {
+		if cfg == nil || profile == "" {
+			continue
+		}
+		if values, ok := cfg.Profiles[profile]; ok {
+			found = true
+			for k, v := range values {
+				merged[k] = v
+			}
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("profile %q not found in any config file", profile)
+	}
+	return merged, nil
+}
+
+// applyConfig sets flag values from the merged config, skipping flags that
+// were given explicitly on the command line or belong to other subcommands.
+func applyConfig(fs *flag.FlagSet, values map[string]any) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for name, value := range values {
+		if name == "profile" {
+			return fmt.Errorf("config: profile cannot be set in a config file")
+		}
+		if fs.Lookup(name) == nil {
+			if knownOption(name) {
+				continue
+			}
+			return fmt.Errorf("config: unknown option %q", name)
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := fs.Set(name, configString(value)); err != nil {
+			return fmt.Errorf("config: invalid value for %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// configString converts a YAML value into its flag string form.
+// Lists are joined with commas to match the comma-separated flags.
+func configString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = configString(item)
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// effectiveConfig returns the flag values as alternating key/value pairs
+// sorted by flag name, suitable for structured logging.
+func effectiveConfig(fs *flag.FlagSet) []any {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	sort.Strings(names)
+	args := make([]any, 0, 2*len(names))
+	for _, name := range names {
+		args = append(args, name, fs.Lookup(name).Value.String())
+	}
+	return args
+}
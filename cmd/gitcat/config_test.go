@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitpath"
+)
+
+func TestMergeConfigs(t *testing.T) {
+	user := &config{
+		Values: map[string]any{"exclude": "vendor", "maxsize": 500},
+		Profiles: map[string]map[string]any{
+			"review": {"keep": ".go", "maxsize": 100},
+		},
+	}
+	repo := &config{
+		Values: map[string]any{"maxsize": 200, "keep": ".md"},
+		Profiles: map[string]map[string]any{
+			"docs": {"keep": ".txt"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		configs []*config
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			configs: []*config{user, repo},
+			want:    map[string]any{"exclude": "vendor", "maxsize": 200, "keep": ".md"},
+		},
+		{
+			name:    "user profile over repo defaults",
+			profile: "review",
+			configs: []*config{user, repo},
+			want:    map[string]any{"exclude": "vendor", "maxsize": 100, "keep": ".go"},
+		},
+		{
+			name:    "repo profile",
+			profile: "docs",
+			configs: []*config{user, repo},
+			want:    map[string]any{"exclude": "vendor", "maxsize": 200, "keep": ".txt"},
+		},
+		{
+			name:    "missing config",
+			configs: []*config{nil, repo},
+			want:    map[string]any{"maxsize": 200, "keep": ".md"},
+		},
+		{
+			name:    "unknown profile",
+			profile: "missing",
+			configs: []*config{user, repo},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeConfigs(tt.profile, tt.configs...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mergeConfigs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("mergeConfigs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRepoOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config
+		wantErr bool
+	}{
+		{
+			name: "selection and format",
+			cfg: config{
+				Values:   map[string]any{"keep": ".go", "exclude": "vendor", "fmt": "md"},
+				Profiles: map[string]map[string]any{"short": {"head": 20}},
+			},
+		},
+		{name: "output file", cfg: config{Values: map[string]any{"out": "/tmp/x"}}, wantErr: true},
+		{name: "ssh key", cfg: config{Values: map[string]any{"ssh-key": "~/.ssh/id"}}, wantErr: true},
+		{name: "token file", cfg: config{Values: map[string]any{"token-file": "token"}}, wantErr: true},
+		{
+			name:    "host keys in profile",
+			cfg:     config{Profiles: map[string]map[string]any{"p": {"strict-host-keys": false}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.checkRepoOptions(); (err != nil) != tt.wantErr {
+				t.Errorf("checkRepoOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepoConfigOptionsExist(t *testing.T) {
+	for _, name := range repoConfigOptions {
+		if !knownOption(name) {
+			t.Errorf("repository config option %q is not a flag of any command", name)
+		}
+	}
+}
+
+func TestUserConfigPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if got, want := userConfigPath(), filepath.Join("/xdg", "gitcat", "config.yaml"); got != want {
+		t.Errorf("userConfigPath() = %q, want %q", got, want)
+	}
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if got, want := userConfigPath(), filepath.Join(home, ".config", "gitcat", "config.yaml"); got != want {
+		t.Errorf("userConfigPath() without XDG_CONFIG_HOME = %q, want %q", got, want)
+	}
+}
+
+func TestEffectiveConfigSizes(t *testing.T) {
+	var minSize, maxSize, unset gitpath.Size = 0, 0, -1
+	fs := flag.NewFlagSet("cat", flag.ContinueOnError)
+	fs.Var(&unset, "unset", "")
+	fs.Var(&minSize, "minsize", "")
+	fs.Var(&maxSize, "maxsize", "")
+	if err := fs.Parse([]string{"-minsize", "100"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig(fs, map[string]any{"maxsize": 500}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := effectiveConfig(fs)
+	want := []any{"maxsize", "500", "minsize", "100", "unset", "-1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("effectiveConfig() = %v, want %v", got, want)
+	}
+	if maxSize.InBytes() != 500*1024 {
+		t.Errorf("maxsize = %d bytes, want %d", maxSize.InBytes(), 500*1024)
+	}
+}
@@ -1,130 +1,114 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
 
 	"github.com/i-zaitsev/gitcat/pkg/files"
 	"github.com/i-zaitsev/gitcat/pkg/log"
-	"github.com/i-zaitsev/gitcat/pkg/ls"
 	"github.com/i-zaitsev/gitcat/pkg/output"
 )
 
+// Exit codes distinguishing why gitcat stopped.
+const (
+	exitError    = 1
+	exitTimeout  = 124
+	exitCanceled = 130
+)
+
 // writeOutput writes content to the specified file or stdout.
 // If outFile is empty, writes to stdout. Otherwise, appends the format extension.
-func writeOutput(content, outFile string, format output.Format) error {
+// The file is written to a temporary name first and only renamed into place if
+// ctx is still active, so a cancelled run never leaves a partial output file.
+func writeOutput(ctx context.Context, content, outFile string, format output.Format) error {
 	if outFile == "" {
 		fmt.Println(content)
 		return nil
 	}
 
 	filename := outFile + "." + string(format)
-	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
 		return fmt.Errorf("failed to write output file: %w", err)
 	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
 
-	log.Info("output written to file", "file", filename)
-	return nil
-}
-
-func main() {
-	cli := NewCLI()
-
-	if err := cli.Parse(os.Args[1:]); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+	_, err = tmp.WriteString(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
 	}
-
-	if cli.dryRun {
-		log.Info("dry run mode enabled - no actions will be executed")
-		if cli.location.IsLocal() {
-			log.Info("would list local repository", "path", cli.location.Path)
-		} else {
-			log.Info("would clone repository",
-				"url", cli.location.Path,
-				"protocol", cli.location.Kind,
-				"dir", cli.localDir)
-			if cli.tmpClone {
-				log.Warn("cloning to a tmp directory - deleted after execution")
-			}
-		}
-		return
+	if err == nil {
+		err = ctx.Err()
 	}
-
-	if cli.location.IsLocal() {
-		log.Info("listing local repository", "path", cli.location.Path)
-	} else {
-		log.Info("cloning repository",
-			"url", cli.location.Path,
-			"protocol", cli.location.Kind,
-			"dir", cli.localDir)
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
 	}
-
-	var (
-		lsErr error
-		repo  *ls.RepoContent
-	)
-
-	list := ls.NewList().
-		IgnoreDotFiles().
-		WithPaths(cli.includePaths...).
-		ExcludePaths(cli.excludePaths...)
-
-	if cli.location.IsLocal() {
-		repo, lsErr = list.LocalRepo(cli.location.Path)
-	} else {
-		cloneDir := cli.localDir
-		if cli.tmpClone {
-			tmpDir, err := os.MkdirTemp("", "gitcat-*")
-			if err != nil {
-				log.Error("failed to create tmp directory", "error", err)
-				os.Exit(1)
-			}
-			defer os.RemoveAll(tmpDir)
-			cloneDir = tmpDir
-		}
-		repo, lsErr = list.RemoteRepo(cli.location, cloneDir)
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
 	}
-
-	if lsErr != nil {
-		log.Error("failed to list repo files", "error", lsErr)
-		os.Exit(1)
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
 	}
 
-	log.Info("successfully listed repo files", "count", len(repo.Files))
+	log.Info("output written to file", "file", filename)
+	return nil
+}
 
-	if len(cli.keepExt) > 0 {
-		log.Warn("keeping only files with extensions", "extensions", cli.keepExt)
-		repo = files.MatchExt(repo, cli.keepExt...)
+// printSelection writes the selected files with their sizes and the total.
+func printSelection(w io.Writer, sizes []files.FileSize) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	var total int64
+	for _, f := range sizes {
+		total += max(f.Size, 0)
+		_, _ = fmt.Fprintf(tw, "%d\t  %s\n", f.Size, f.Path)
 	}
+	_, _ = fmt.Fprintf(tw, "%d\t  total (%d files)\n", total, len(sizes))
+	return tw.Flush()
+}
 
-	if cli.minSize > 0 || cli.maxSize >= 0 {
-		log.Info("applying size filters", "minsize", cli.minSize.InBytes(), "maxsize", cli.maxSize.InBytes())
-		repo = files.FilterBySize(repo, cli.minSize.InBytes(), cli.maxSize.InBytes())
-	}
+func main() {
+	os.Exit(run())
+}
 
-	log.Info("files after all filters", "count", len(repo.Files))
+// run parses the arguments and executes the command until it completes, times out,
+// or is interrupted by SIGINT or SIGTERM. Cleanup is deferred inside the command,
+// so it has finished by the time run returns the exit code.
+func run() int {
+	cli := NewCLI()
 
-	var content string
-	switch cli.outFmt {
-	case output.FormatJSONL:
-		log.Info("writing output to FormatGrouped")
-		jsonl, err := output.ToJSONL(repo, cli.headLines)
-		if err != nil {
-			log.Error("failed to generate JSONL output", "error", err)
-			os.Exit(1)
+	if err := cli.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
 		}
-		content = jsonl
-	case output.FormatText:
-		log.Info("writing output to text")
-		content = output.ToText(repo, cli.headLines)
-	case output.FormatMarkdown:
-		log.Info("writing output to markdown")
-		content = output.ToMarkdown(repo, cli.headLines)
+		_, _ = fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return exitError
 	}
 
-	if err := writeOutput(content, cli.outFile, cli.outFmt); err != nil {
-		log.Error("failed to write output", "error", err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Restoring the default signal handling once the first signal arrives
+	// lets a second Ctrl-C kill a command that is slow to wind down.
+	context.AfterFunc(ctx, stop)
+
+	err := cli.Run(ctx)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Error("command timed out", "command", cli.command.name, "timeout", cli.timeout)
+		return exitTimeout
+	case ctx.Err() != nil:
+		log.Error("command interrupted", "command", cli.command.name)
+		return exitCanceled
+	case err != nil:
+		log.Error("command failed", "command", cli.command.name, "error", err)
+		return exitError
 	}
+	return 0
 }
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/i-zaitsev/gitcat/pkg/files"
+	"github.com/i-zaitsev/gitcat/pkg/gitattr"
+	"github.com/i-zaitsev/gitcat/pkg/gitclone"
+	"github.com/i-zaitsev/gitcat/pkg/gitpath"
+	"github.com/i-zaitsev/gitcat/pkg/log"
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+// maxParallelRepos bounds the number of repositories cloned and listed at once.
+const maxParallelRepos = 4
+
+// target is a repository given on the command line, with the options
+// that depend on its location.
+type target struct {
+	name      string // prefix of its files when several repositories are combined
+	location  *gitpath.GitPath
+	localDir  string
+	cloneOpts gitclone.Options
+	paths     []string
+	sparse    bool // check out only paths
+}
+
+// newTargets parses the repository locations and derives the clone options
+// of each from the command-line options.
+func (c *Cli) newTargets(locations []string) ([]*target, error) {
+	targets := make([]*target, 0, len(locations))
+	var tokenHosts []string
+	for _, loc := range locations {
+		location, err := gitpath.Parse(loc, c.shorthand)
+		if err != nil {
+			return nil, err
+		}
+		if c.from == fromIndex && !location.IsLocal() {
+			return nil, fmt.Errorf("-from index requires a local repository: %s", location.Redacted())
+		}
+		t := &target{
+			location:  location,
+			cloneOpts: c.cloneOpts,
+			paths:     c.includePaths,
+			sparse:    c.sparse,
+		}
+		if !location.IsLocal() {
+			if t.cloneOpts.Auth.Token, err = gitclone.ResolveToken(location, c.tokenFile); err != nil {
+				return nil, err
+			}
+			if t.cloneOpts.Auth.Token != "" && !slices.Contains(tokenHosts, location.Host) {
+				tokenHosts = append(tokenHosts, location.Host)
+			}
+		}
+		if location.RefPath == "" || t.cloneOpts.Ref != "" {
+			t.applyLocationRef(location.SplitRefPath([]string{t.cloneOpts.Ref}))
+		}
+		targets = append(targets, t)
+	}
+	if len(tokenHosts) > 1 && (c.tokenFile != "" || os.Getenv("GITCAT_TOKEN") != "") {
+		log.Warn("the token from -token-file or $GITCAT_TOKEN is sent to every HTTPS host", "hosts", strings.Join(tokenHosts, ","))
+	}
+
+	nameTargets(targets)
+	for _, t := range targets {
+		switch {
+		case len(targets) == 1 && c.localDir != "":
+			t.localDir = c.localDir
+		case c.localDir != "":
+			t.localDir = filepath.Join(c.localDir, filepath.FromSlash(t.name))
+		default:
+			t.localDir = filepath.FromSlash(t.name)
+		}
+	}
+	return targets, nil
+}
+
+// nameTargets names each repository after its repo name, qualified with
+// its owner when two repositories share a name, and numbered if that
+// is still not enough to tell them apart.
+func nameTargets(targets []*target) {
+	count := make(map[string]int, len(targets))
+	for _, t := range targets {
+		count[inferName(t.location)]++
+	}
+	seen := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		t.name = inferName(t.location)
+		if count[t.name] > 1 && t.location.Owner != "" {
+			t.name = t.location.Owner + "/" + t.name
+		}
+		for i, base := 2, t.name; seen[t.name]; i++ {
+			t.name = base + "-" + strconv.Itoa(i)
+		}
+		seen[t.name] = true
+	}
+}
+
+func inferName(location *gitpath.GitPath) string {
+	if location.Repo == "" {
+		return "repo"
+	}
+	return location.Repo
+}
+
+// applyLocationRef checks out the ref and selects the path of a web URL
+// pointing into a repository, or the ref of an owner/repo@ref shorthand.
+// An explicit -ref takes precedence.
+func (t *target) applyLocationRef(ref, sub string) {
+	if ref != "" {
+		if t.cloneOpts.Ref == "" {
+			t.cloneOpts.Ref = ref
+		} else if t.cloneOpts.Ref != ref {
+			log.Warn("ignoring ref from repository location", "location_ref", ref, "ref", t.cloneOpts.Ref)
+		}
+	}
+	if sub != "" {
+		t.paths = append(slices.Clone(t.paths), sub)
+	}
+	if t.sparse {
+		t.cloneOpts.Sparse = t.paths
+	}
+	if ref != "" || sub != "" {
+		log.Info("using ref and path from repository location", "url", t.location.Redacted(),
+			"ref", t.cloneOpts.Ref, "path", sub)
+	}
+}
+
+// resolveLocationRef splits the ref and path of a web URL whose ref may
+// contain slashes at the longest branch or tag of the remote, unless -ref
+// already told where the ref ends.
+func (t *target) resolveLocationRef(ctx context.Context) error {
+	if t.location.RefPath == "" || t.cloneOpts.Ref != "" {
+		return nil
+	}
+	refs, err := gitclone.RemoteRefs(ctx, t.location, t.cloneOpts.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to list the refs of %s: %w", t.location.Redacted(), err)
+	}
+	t.applyLocationRef(t.location.SplitRefPath(refs))
+	return nil
+}
+
+// readRepoList reads repository locations from a file, one per line.
+// Blank lines and lines starting with # are ignored.
+func readRepoList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository list: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var locations []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		locations = append(locations, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read repository list: %w", err)
+	}
+	return locations, nil
+}
+
+// selectFiles selects the files of all repositories. Several repositories are
+// combined into one whose file names are prefixed with the repository names.
+// It returns a nil repo in dry run mode, see selectRepos.
+func (c *Cli) selectFiles(ctx context.Context) (*ls.RepoContent, func(), error) {
+	repos, cleanup, err := c.selectRepos(ctx)
+	if err != nil || repos == nil {
+		return nil, nil, err
+	}
+	return c.combine(repos), cleanup, nil
+}
+
+// selectRepos lists the repositories concurrently and applies the extension
+// and size filters to each. In dry run mode local repositories go through the
+// full selection and the selected files are printed with their sizes, while
+// remote repositories are not cloned; in both cases nil repos are returned.
+// The returned cleanup function removes the temporary clones, if any.
+func (c *Cli) selectRepos(ctx context.Context) ([]*ls.RepoContent, func(), error) {
+	if c.dryRun {
+		log.Info("dry run mode enabled - no actions will be executed")
+		log.Info("effective configuration", effectiveConfig(c.flags)...)
+	}
+
+	var (
+		repos    = make([]*ls.RepoContent, len(c.targets))
+		cleanups = make([]func(), len(c.targets))
+		errs     = make([]error, len(c.targets))
+		sem      = make(chan struct{}, maxParallelRepos)
+		wg       sync.WaitGroup
+	)
+	for i, t := range c.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			repos[i], cleanups[i], errs[i] = c.selectRepo(ctx, t)
+			if errs[i] != nil && len(c.targets) > 1 {
+				errs[i] = fmt.Errorf("%s: %w", t.name, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	cleanup := func() {
+		for _, f := range cleanups {
+			if f != nil {
+				f()
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	if c.dryRun {
+		defer cleanup()
+		if !slices.ContainsFunc(repos, func(r *ls.RepoContent) bool { return r != nil }) {
+			return nil, nil, nil
+		}
+		return nil, nil, printSelection(os.Stdout, files.Sizes(c.combine(repos)))
+	}
+	return repos, cleanup, nil
+}
+
+// combine merges the repositories selected for the targets, skipping the
+// ones that were not listed. A single repository is returned unchanged.
+func (c *Cli) combine(repos []*ls.RepoContent) *ls.RepoContent {
+	if len(repos) == 1 {
+		return repos[0]
+	}
+	var (
+		names  []string
+		listed []*ls.RepoContent
+	)
+	for i, repo := range repos {
+		if repo != nil {
+			names = append(names, c.targets[i].name)
+			listed = append(listed, repo)
+		}
+	}
+	return ls.Combine(names, listed)
+}
+
+// keptMarks returns the .gitattributes attributes whose files are included.
+func (c *Cli) keptMarks() []string {
+	var attrs []string
+	for _, m := range []struct {
+		attr string
+		keep bool
+	}{
+		{gitattr.Generated, c.keepGenerated},
+		{gitattr.Vendored, c.keepVendored},
+		{gitattr.Documentation, c.keepDocs},
+		{gitattr.ExportIgnore, c.keepExportIgnored},
+	} {
+		if m.keep {
+			attrs = append(attrs, m.attr)
+		}
+	}
+	return attrs
+}
+
+// selectRepo lists a single repository and applies the extension and size filters.
+// In dry run mode it returns a nil repo for remote repositories without cloning them.
+func (c *Cli) selectRepo(ctx context.Context, t *target) (*ls.RepoContent, func(), error) {
+	if c.dryRun && !t.location.IsLocal() && !t.location.IsArchive() {
+		log.Info("would clone repository",
+			"url", t.location.Redacted(),
+			"protocol", t.location.Kind,
+			"dir", t.localDir)
+		if t.location.RefPath != "" && t.cloneOpts.Ref == "" {
+			log.Info("the ref and path of the link are split at the remote's longest matching ref when cloning",
+				"ref_path", t.location.RefPath)
+		}
+		if c.tmpClone {
+			log.Warn("cloning to a tmp directory - deleted after execution")
+		}
+		return nil, nil, nil
+	}
+	if err := t.resolveLocationRef(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case t.location.IsLocal():
+		log.Info("listing local repository", "path", t.location.Path)
+	case t.location.IsArchive():
+		log.Info("listing archive", "path", t.location.Path)
+	case t.location.IsBundle():
+		log.Info("cloning bundle", "path", t.location.Path, "dir", t.localDir)
+	default:
+		log.Info("cloning repository",
+			"url", t.location.Redacted(),
+			"protocol", t.location.Kind,
+			"dir", t.localDir)
+	}
+
+	var (
+		lsErr   error
+		repo    *ls.RepoContent
+		cleanup = func() {}
+	)
+
+	cloneOpts := t.cloneOpts
+	if t.location.IsBundle() {
+		// A bundle is a local file holding every object it was created with,
+		// so it is cloned in full, without credentials or a cached mirror.
+		cloneOpts.Depth, cloneOpts.Filter, cloneOpts.Sparse = 0, "", nil
+		cloneOpts.Auth = gitclone.Auth{}
+	}
+
+	list := ls.NewList().
+		IgnoreDotFiles().
+		WithPaths(t.paths...).
+		ExcludePaths(c.excludePaths...).
+		CloneOptions(cloneOpts).
+		FromRev(c.rev).
+		WithSymlinks(c.symlinks).
+		KeepMarked(c.keptMarks()...)
+	if c.from == fromIndex {
+		list.FromIndex()
+	}
+
+	if c.useCache && !t.location.IsBundle() {
+		list.UseCache(c.cache())
+	}
+
+	switch {
+	case t.location.IsLocal():
+		repo, lsErr = list.LocalRepo(ctx, t.location.Path)
+	case t.location.IsArchive():
+		repo, lsErr = list.Archive(ctx, t.location.Path)
+	default:
+		cloneDir := t.localDir
+		if c.tmpClone {
+			tmpDir, err := os.MkdirTemp("", "gitcat-*")
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create tmp directory: %w", err)
+			}
+			cleanup = func() { _ = os.RemoveAll(tmpDir) }
+			cloneDir = tmpDir
+		}
+		repo, lsErr = list.RemoteRepo(ctx, t.location, cloneDir)
+	}
+
+	if existsErr := (*gitclone.ExistsError)(nil); errors.As(lsErr, &existsErr) {
+		cleanup()
+		return nil, nil, fmt.Errorf("%w; use -reuse to update it, -force to replace it, or pick another -dir", lsErr)
+	} else if errors.Is(lsErr, gitclone.ErrUncommitted) {
+		cleanup()
+		return nil, nil, fmt.Errorf("%w; commit or stash them, use -force to replace the clone, or pick another -dir", lsErr)
+	} else if lsErr != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to list repo files: %w", lsErr)
+	}
+
+	if closer, ok := repo.FS.(io.Closer); ok {
+		removeClone := cleanup
+		cleanup = func() {
+			_ = closer.Close()
+			removeClone()
+		}
+	}
+
+	log.Info("successfully listed repo files", "count", len(repo.Files))
+
+	if len(c.keepExt) > 0 {
+		log.Warn("keeping only files with extensions", "extensions", c.keepExt)
+		repo = files.MatchExt(repo, c.keepExt...)
+	}
+
+	// A dry run prints no tags, and reads the pointer files only when the
+	// sizes of their objects are filtered on or the pointer files are left out.
+	sizeFiltered := c.minSize > 0 || c.maxSize >= 0
+	repo = files.Classify(repo, c.noGenerated, c.noVendor, c.writesTags() && !c.dryRun)
+	if !c.dryRun || sizeFiltered || c.lfs == files.LFSSkip {
+		repo = files.ResolveLFS(ctx, repo, c.lfs, cloneOpts.Auth)
+	}
+
+	if sizeFiltered {
+		log.Info("applying size filters", "minsize_kb", c.minSize.String(), "maxsize_kb", c.maxSize.String())
+		repo = files.FilterBySize(repo, c.minSize.InBytes(), c.maxSize.InBytes())
+	}
+
+	log.Info("files after all filters", "count", len(repo.Files))
+	return repo, cleanup, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/i-zaitsev/gitcat/pkg/files"
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+func TestSelectRepoDryRun(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	pointer := "version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:" + strings.Repeat("a", 64) + "\n" +
+		"size 5000\n"
+	for name, data := range map[string]string{
+		"main.go":   "package main\n",
+		"gen.go":    "// Code generated by gen. DO NOT EDIT.\n\npackage main\n",
+		"model.bin": pointer,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantTags []string // tags of gen.go
+		want     string   // printed selection
+	}{
+		{
+			name: "dry run",
+			args: []string{"-dryrun", "-fmt", "md"},
+			want: "" +
+				"   53  gen.go\n" +
+				"   13  main.go\n" +
+				"  129  model.bin\n" +
+				"  195  total (3 files)\n",
+		},
+		{
+			name: "dry run with a size filter",
+			args: []string{"-dryrun", "-fmt", "md", "-maxsize", "1"},
+			want: "" +
+				"  53  gen.go\n" +
+				"  13  main.go\n" +
+				"  66  total (2 files)\n",
+		},
+		{
+			name:     "cat",
+			args:     []string{"-fmt", "md"},
+			wantTags: []string{ls.TagGenerated},
+			want: "" +
+				"    53  gen.go\n" +
+				"    13  main.go\n" +
+				"  5000  model.bin\n" +
+				"  5066  total (3 files)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCLI()
+			if err := c.Parse(append(tt.args, dir)); err != nil {
+				t.Fatal(err)
+			}
+			repo, cleanup, err := c.selectRepo(context.Background(), c.targets[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanup()
+
+			if got := repo.Attr("gen.go").Tags; !slices.Equal(got, tt.wantTags) {
+				t.Errorf("tags of gen.go = %q, want %q", got, tt.wantTags)
+			}
+			var buf bytes.Buffer
+			if err := printSelection(&buf, files.Sizes(repo)); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("printSelection() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
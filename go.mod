@@ -3,6 +3,8 @@ module github.com/i-zaitsev/gitcat
 go 1.25
 
 require (
-	golang.org/x/sys v0.39.0 // indirect
-	golang.org/x/term v0.38.0 // indirect
+	golang.org/x/term v0.38.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.39.0 // indirect
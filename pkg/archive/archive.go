@@ -0,0 +1,240 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/i-zaitsev/gitcat/pkg/internal/memfs"
+	"github.com/i-zaitsev/gitcat/pkg/internal/utils"
+	"github.com/i-zaitsev/gitcat/pkg/log"
+)
+
+// suffixes lists the archive file names Open understands.
+var suffixes = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// IsArchive reports whether the file name has a supported archive suffix.
+func IsArchive(name string) bool {
+	return Name(name) != name
+}
+
+// Name returns the file name without its archive suffix, e.g. repo-1.2.3
+// for repo-1.2.3.tar.gz.
+func Name(name string) string {
+	lower := strings.ToLower(name)
+	for _, s := range suffixes {
+		if strings.HasSuffix(lower, s) && len(name) > len(s) {
+			return name[:len(name)-len(s)]
+		}
+	}
+	return name
+}
+
+// Size limits of the entries read from an archive, whose contents are
+// held in memory. Larger entries are left out with a warning; archives
+// whose kept entries add up to more than maxTotalSize are refused.
+var (
+	maxEntrySize int64 = 64 << 20
+	maxTotalSize int64 = 1 << 30
+)
+
+// maxLinkSize bounds the target of a symbolic link stored in a zip entry.
+const maxLinkSize = 4096
+
+// Open returns the files of a .tar, .tar.gz, .tgz or .zip archive as a
+// read-only file system. Entries are read without extracting them to disk:
+// zip entries are decompressed when opened, tarballs are decompressed into
+// memory as they are streamed, subject to the size limits above.
+// If all entries share a single top-level directory, as in release
+// tarballs (repo-1.2.3/...), the file system is rooted in it.
+func Open(name string) (fs.FS, error) {
+	var (
+		fsys fs.FS
+		err  error
+	)
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		fsys, err = readZip(name)
+	case strings.HasSuffix(lower, ".tar"):
+		fsys, err = openTar(name, false)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		fsys, err = openTar(name, true)
+	default:
+		return nil, fmt.Errorf("unsupported archive %s: must be one of %s", name, strings.Join(suffixes, ", "))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", name, err)
+	}
+	return stripTopDir(fsys)
+}
+
+// openTar streams the tarball from disk, decompressing it if gzipped.
+func openTar(name string, gzipped bool) (fs.FS, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.SilentClose(f)
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer utils.SilentClose(gz)
+		r = gz
+	}
+	return readTar(r)
+}
+
+// readTar loads the regular files and symbolic links of a tar stream into memory.
+func readTar(r io.Reader) (fs.FS, error) {
+	fsys := memfs.New()
+	tr := tar.NewReader(r)
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fsys, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeSymlink {
+			continue
+		}
+		name, err := entryName(hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			target := []byte(hdr.Linkname)
+			fsys.Add(name, int64(len(target)), fs.ModeSymlink|0777, hdr.ModTime, memfs.Bytes(target))
+			continue
+		}
+		if !withinLimits(name, hdr.Size, &total) {
+			continue
+		} else if total > maxTotalSize {
+			return nil, totalSizeError()
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		fsys.Add(name, int64(len(data)), hdr.FileInfo().Mode(), hdr.ModTime, memfs.Bytes(data))
+	}
+}
+
+// readZip lists the entries of a zip file, which are decompressed when opened.
+// The compressed file is held in memory, so it is subject to maxTotalSize too.
+// Symbolic links are read up front so that their targets can be resolved.
+func readZip(name string) (fs.FS, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	} else if info.Size() > maxTotalSize {
+		return nil, totalSizeError()
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	fsys := memfs.New()
+	var total int64
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		if mode.IsDir() || !mode.IsRegular() && mode&fs.ModeSymlink == 0 {
+			continue
+		}
+		name, err := entryName(zf.Name)
+		if err != nil {
+			return nil, err
+		}
+		if mode&fs.ModeSymlink != 0 {
+			target, err := readZipEntry(zf, maxLinkSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read link %s: %w", name, err)
+			}
+			fsys.Add(name, int64(len(target)), fs.ModeSymlink|0777, zf.Modified, memfs.Bytes(target))
+			continue
+		}
+		size := int64(zf.UncompressedSize64)
+		if !withinLimits(name, size, &total) {
+			continue
+		} else if total > maxTotalSize {
+			return nil, totalSizeError()
+		}
+		fsys.Add(name, size, mode, zf.Modified, func() ([]byte, error) {
+			return readZipEntry(zf, size)
+		})
+	}
+	return fsys, nil
+}
+
+// readZipEntry decompresses a zip entry, failing if it holds more than limit bytes,
+// so that an entry whose header understates its size cannot exhaust memory.
+func readZipEntry(zf *zip.File, limit int64) ([]byte, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer utils.SilentClose(rc)
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	} else if int64(len(data)) > limit {
+		return nil, fmt.Errorf("entry %s is larger than %d bytes", zf.Name, limit)
+	}
+	return data, nil
+}
+
+// entryName cleans the name of an archive entry, rejecting names that
+// are absolute or point outside the archive.
+func entryName(name string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(name, "./"))
+	if !fs.ValidPath(cleaned) || cleaned == "." {
+		return "", fmt.Errorf("invalid entry name %q", name)
+	}
+	return cleaned, nil
+}
+
+// withinLimits reports whether an entry of the given size is kept, adding
+// its size to total if so. Entries over maxEntrySize are left out.
+func withinLimits(name string, size int64, total *int64) bool {
+	if size > maxEntrySize {
+		log.Warn("skipping large archive entry", "file", name, "size", size, "limit", maxEntrySize)
+		return false
+	}
+	*total += size
+	return true
+}
+
+func totalSizeError() error {
+	return fmt.Errorf("archive contents exceed %d bytes", maxTotalSize)
+}
+
+// stripTopDir roots the file system in its top-level directory if that is its only entry.
+func stripTopDir(fsys fs.FS) (fs.FS, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return fsys, nil
+	}
+	return fs.Sub(fsys, entries[0].Name())
+}
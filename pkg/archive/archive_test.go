@@ -0,0 +1,272 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// entry is a file or, when link is set, a symbolic link written to a test archive.
+type entry struct {
+	name, data, link string
+}
+
+// writeArchive writes the entries to an archive named after its format.
+func writeArchive(t *testing.T, name string, entries []entry) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if strings.HasSuffix(name, ".zip") {
+		zw := zip.NewWriter(f)
+		for _, e := range entries {
+			hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+			data := e.data
+			if e.link != "" {
+				hdr.SetMode(fs.ModeSymlink | 0777)
+				data = e.link
+			}
+			w, err := zw.CreateHeader(hdr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.WriteString(w, data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	var w io.Writer = f
+	if !strings.HasSuffix(name, ".tar") {
+		gz := gzip.NewWriter(f)
+		defer func() {
+			if err := gz.Close(); err != nil {
+				t.Fatal(err)
+			}
+		}()
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}
+		if e.link != "" {
+			hdr = &tar.Header{Name: e.name, Mode: 0777, Linkname: e.link, Typeflag: tar.TypeSymlink}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, e.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// listFiles returns the names of the files and links of fsys.
+func listFiles(t *testing.T, fsys fs.FS) []string {
+	t.Helper()
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			names = append(names, p)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+var formats = []string{"repo.tar", "repo.tar.gz", "repo.tgz", "repo.zip"}
+
+func TestOpen(t *testing.T) {
+	entries := []entry{
+		{name: "repo-1.0/README.md", data: "# Repo\n"},
+		{name: "./repo-1.0/pkg/a.go", data: "package pkg\n"},
+		{name: "repo-1.0/docs/link.md", link: "../README.md"},
+	}
+	for _, format := range formats {
+		t.Run(format, func(t *testing.T) {
+			fsys, err := Open(writeArchive(t, format, entries))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []string{"README.md", "docs/link.md", "pkg/a.go"}
+			if got := listFiles(t, fsys); !slices.Equal(got, want) {
+				t.Errorf("files = %q, want %q", got, want)
+			}
+			data, err := fs.ReadFile(fsys, "pkg/a.go")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "package pkg\n" {
+				t.Errorf("pkg/a.go = %q, want %q", data, "package pkg\n")
+			}
+			target, err := fs.ReadLink(fsys, "docs/link.md")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if target != "../README.md" {
+				t.Errorf("ReadLink(docs/link.md) = %q, want %q", target, "../README.md")
+			}
+			info, err := fs.Lstat(fsys, "docs/link.md")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode()&fs.ModeSymlink == 0 {
+				t.Errorf("Lstat(docs/link.md).Mode() = %v, want a symbolic link", info.Mode())
+			}
+		})
+	}
+}
+
+func TestOpenStripTopDir(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+		want    []string
+	}{
+		{
+			"single top-level directory",
+			[]entry{{name: "top/a.txt", data: "a"}, {name: "top/sub/b.txt", data: "b"}},
+			[]string{"a.txt", "sub/b.txt"},
+		},
+		{
+			"several top-level entries",
+			[]entry{{name: "top/a.txt", data: "a"}, {name: "b.txt", data: "b"}},
+			[]string{"b.txt", "top/a.txt"},
+		},
+		{
+			"single top-level file",
+			[]entry{{name: "a.txt", data: "a"}},
+			[]string{"a.txt"},
+		},
+	}
+	for _, tt := range tests {
+		for _, format := range formats {
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				fsys, err := Open(writeArchive(t, format, tt.entries))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := listFiles(t, fsys); !slices.Equal(got, tt.want) {
+					t.Errorf("files = %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestOpenInvalidNames(t *testing.T) {
+	for _, name := range []string{"../x", "top/../../x", "/etc/x"} {
+		for _, format := range formats {
+			t.Run(name+"/"+format, func(t *testing.T) {
+				file := writeArchive(t, format, []entry{{name: "ok.txt", data: "ok"}, {name: name, data: "x"}})
+				if _, err := Open(file); err == nil || !strings.Contains(err.Error(), "invalid entry name") {
+					t.Errorf("Open() error = %v, want an invalid entry name", err)
+				}
+			})
+		}
+	}
+}
+
+func TestOpenSizeLimits(t *testing.T) {
+	defer func(entry, total int64) { maxEntrySize, maxTotalSize = entry, total }(maxEntrySize, maxTotalSize)
+	maxEntrySize, maxTotalSize = 1000, 1500
+
+	for _, format := range formats {
+		t.Run(format, func(t *testing.T) {
+			fsys, err := Open(writeArchive(t, format, []entry{
+				{name: "small.txt", data: "small"},
+				{name: "large.txt", data: strings.Repeat("l", 1001)},
+				{name: "exact.txt", data: strings.Repeat("e", 1000)},
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := listFiles(t, fsys), []string{"exact.txt", "small.txt"}; !slices.Equal(got, want) {
+				t.Errorf("files = %q, want %q", got, want)
+			}
+
+			_, err = Open(writeArchive(t, format, []entry{
+				{name: "a.txt", data: strings.Repeat("a", 800)},
+				{name: "b.txt", data: strings.Repeat("b", 800)},
+			}))
+			if err == nil || !strings.Contains(err.Error(), "exceed") {
+				t.Errorf("Open() error = %v, want the total size exceeded", err)
+			}
+		})
+	}
+}
+
+func TestReadZipEntryLimit(t *testing.T) {
+	zr, err := zip.OpenReader(writeArchive(t, "repo.zip", []entry{{name: "a.txt", data: "0123456789"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	if data, err := readZipEntry(zr.File[0], 10); err != nil || string(data) != "0123456789" {
+		t.Errorf("readZipEntry(limit=10) = %q, %v, want the content", data, err)
+	}
+	if _, err := readZipEntry(zr.File[0], 9); err == nil {
+		t.Error("readZipEntry(limit=9) succeeded, want an error for the entry over the limit")
+	}
+}
+
+func TestOpenUnsupported(t *testing.T) {
+	if _, err := Open("repo.rar"); err == nil {
+		t.Error("Open(repo.rar) succeeded, want an error")
+	}
+}
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		name        string
+		want        string
+		wantArchive bool
+	}{
+		{"repo-1.2.3.tar.gz", "repo-1.2.3", true},
+		{"repo.TGZ", "repo", true},
+		{"repo.tar", "repo", true},
+		{"dir/repo.zip", "dir/repo", true},
+		{".zip", ".zip", false},
+		{"repo.gz", "repo.gz", false},
+		{"repo", "repo", false},
+	}
+	for _, tt := range tests {
+		if got := Name(tt.name); got != tt.want {
+			t.Errorf("Name(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		if got := IsArchive(tt.name); got != tt.wantArchive {
+			t.Errorf("IsArchive(%q) = %v, want %v", tt.name, got, tt.wantArchive)
+		}
+	}
+}
@@ -3,11 +3,14 @@ package files
 import (
 	"bufio"
 	"bytes"
-	"os"
+	"context"
+	"fmt"
+	"io"
 	"sync"
 
 	"github.com/i-zaitsev/gitcat/pkg/internal/utils"
 	"github.com/i-zaitsev/gitcat/pkg/log"
+	"github.com/i-zaitsev/gitcat/pkg/ls"
 )
 
 type concat struct {
@@ -16,44 +19,120 @@ type concat struct {
 	mu    sync.Mutex
 }
 
-// Cat reads files and concatenates their contents.
-// If maxLines > 0, only the first maxLines of each file are read.
-func Cat(maxLines int, paths ...string) string {
+// maxOpenFiles bounds the number of files Cat reads concurrently.
+const maxOpenFiles = 32
+
+// maxLineSize is the longest line Cat reads, e.g. of a minified file.
+// It is a variable so that tests can lower it.
+var maxLineSize = 64 * 1024 * 1024
+
+// Cat reads files of the repository and concatenates their contents.
+// The lines selector picks which lines of each file are kept; its zero value keeps all of them.
+// Reading stops early with the context's error when ctx is done.
+func Cat(ctx context.Context, repo *ls.RepoContent, lines Lines, paths ...string) (string, error) {
 	cc := concat{
 		paths: paths,
 		lines: make(map[string][]string, len(paths)),
 	}
 	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxOpenFiles)
 	for _, path := range paths {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
 		wg.Add(1)
 		go func(p string) {
-			defer wg.Done()
-			f, err := os.Open(p)
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			_, rel := repo.Resolve(p)
+			f, err := repo.FS.Open(p)
 			if err != nil {
 				log.Warn("failed to open file", "path", p, "error", err)
 				return
 			}
 			defer utils.SilentClose(f)
 			log.Debug("reading file", "path", p)
-			scanner := bufio.NewScanner(f)
-			lineCount := 0
-			for scanner.Scan() {
-				if maxLines > 0 && lineCount >= maxLines {
-					break
-				}
-				cc.mu.Lock()
-				cc.lines[p] = append(cc.lines[p], scanner.Text()+"\n")
-				cc.mu.Unlock()
-				lineCount++
+			read, err := readLines(ctx, f, lines.For(rel))
+			if err != nil {
+				log.Warn("failed to read file", "path", p, "error", err)
+				return
 			}
+			cc.mu.Lock()
+			cc.lines[p] = read
+			cc.mu.Unlock()
 		}(path)
 	}
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 	var buf bytes.Buffer
 	for _, path := range paths {
 		for _, line := range cc.lines[path] {
 			buf.WriteString(line)
 		}
 	}
-	return buf.String()
+	return buf.String(), nil
+}
+
+// readLines scans r and returns the lines selected by the window.
+// When both Head and Tail are set and lines are dropped in between,
+// an elision marker is inserted to make the gap explicit.
+// Scanning stops when ctx is done. Lines longer than maxLineSize are
+// reported as an error rather than truncating the file.
+func readLines(ctx context.Context, r io.Reader, w Window) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, min(64*1024, maxLineSize)), maxLineSize)
+
+	var (
+		head  []string
+		tail  []string
+		total int
+	)
+	for scanner.Scan() && ctx.Err() == nil {
+		total++
+		line := scanner.Text() + "\n"
+
+		if !w.Range.IsZero() {
+			if w.Range.End > 0 && total > w.Range.End {
+				break
+			}
+			if w.Range.Contains(total) {
+				head = append(head, line)
+			}
+			continue
+		}
+
+		if w.Tail == 0 {
+			if w.Head > 0 && total > w.Head {
+				break
+			}
+			head = append(head, line)
+			continue
+		}
+
+		if total <= w.Head {
+			head = append(head, line)
+			continue
+		}
+		tail = append(tail, line)
+		if len(tail) > w.Tail {
+			tail = tail[1:]
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if omitted := total - len(head) - len(tail); w.Range.IsZero() && w.Head > 0 && w.Tail > 0 && omitted > 0 {
+		head = append(head, fmt.Sprintf("... %d lines omitted ...\n", omitted))
+	}
+	return append(head, tail...), nil
 }
@@ -0,0 +1,93 @@
+package files
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+// testContent lists every file of fsys.
+func testContent(fsys fstest.MapFS) *ls.RepoContent {
+	var names []string
+	for name := range fsys {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return &ls.RepoContent{Root: "repo", Files: names, FS: fsys}
+}
+
+func TestCat(t *testing.T) {
+	repo := testContent(fstest.MapFS{
+		"a.txt":     {Data: []byte("a1\na2\na3\n")},
+		"b.txt":     {Data: []byte("b1\nb2")},
+		"pkg/c.txt": {Data: []byte("c1\nc2\nc3\nc4\n")},
+	})
+
+	tests := []struct {
+		name  string
+		lines Lines
+		paths []string
+		want  string
+	}{
+		{
+			name:  "all lines in order",
+			paths: []string{"pkg/c.txt", "a.txt", "b.txt"},
+			want:  "c1\nc2\nc3\nc4\na1\na2\na3\nb1\nb2\n",
+		},
+		{
+			name:  "head",
+			lines: Lines{Window: Window{Head: 1}},
+			paths: []string{"a.txt", "pkg/c.txt"},
+			want:  "a1\nc1\n",
+		},
+		{
+			name:  "path range overrides the window",
+			lines: Lines{Window: Window{Head: 1}, Paths: map[string]LineRange{"pkg": {Start: 2, End: 3}}},
+			paths: []string{"a.txt", "pkg/c.txt"},
+			want:  "a1\nc2\nc3\n",
+		},
+		{
+			name:  "missing files are skipped",
+			paths: []string{"missing.txt", "b.txt"},
+			want:  "b1\nb2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Cat(context.Background(), repo, tt.lines, tt.paths...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Cat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatCombined(t *testing.T) {
+	a := testContent(fstest.MapFS{"main.go": {Data: []byte("a1\na2\na3\n")}})
+	b := testContent(fstest.MapFS{"main.go": {Data: []byte("b1\nb2\nb3\n")}})
+	combined := ls.Combine([]string{"a", "b"}, []*ls.RepoContent{a, b})
+	lines := Lines{Paths: map[string]LineRange{"main.go": {Start: 3}}}
+
+	got, err := Cat(context.Background(), combined, lines, combined.Files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a3\nb3\n"; got != want {
+		t.Errorf("Cat() = %q, want %q", got, want)
+	}
+}
+
+func TestCatCanceled(t *testing.T) {
+	repo := testContent(fstest.MapFS{"a.txt": {Data: []byte("a\n")}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Cat(ctx, repo, Lines{}, repo.Files...); err == nil {
+		t.Error("Cat with a canceled context succeeded")
+	}
+}
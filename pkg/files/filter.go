@@ -1,8 +1,7 @@
 package files
 
 import (
-	"os"
-	"path/filepath"
+	"io/fs"
 
 	"github.com/i-zaitsev/gitcat/pkg/log"
 	"github.com/i-zaitsev/gitcat/pkg/ls"
@@ -11,13 +10,10 @@ import (
 // FilterBySize returns a new RepoContent containing only files within the size range.
 // minSize and maxSize are in bytes. Use 0 for no minimum, -1 for no maximum.
 func FilterBySize(content *ls.RepoContent, minSize, maxSize int64) *ls.RepoContent {
-	var filtered ls.RepoContent
-	filtered.Root = content.Root
+	var filtered []string
 
 	for _, relPath := range content.Files {
-		absPath := filepath.Join(content.Root, relPath)
-
-		info, err := os.Stat(absPath)
+		info, err := fs.Stat(content.FS, relPath)
 		if err != nil {
 			log.Warn("failed to stat file for size filtering", "file", relPath, "error", err)
 			continue
@@ -35,9 +31,31 @@ func FilterBySize(content *ls.RepoContent, minSize, maxSize int64) *ls.RepoConte
 			continue
 		}
 
-		filtered.Files = append(filtered.Files, relPath)
+		filtered = append(filtered, relPath)
 	}
 
-	log.Info("size filtering completed", "input", len(content.Files), "output", len(filtered.Files))
-	return &filtered
+	log.Info("size filtering completed", "input", len(content.Files), "output", len(filtered))
+	return content.WithFiles(filtered)
+}
+
+// FileSize is a repository file with its size in bytes.
+type FileSize struct {
+	Path string
+	Size int64
+}
+
+// Sizes returns the size of every file without reading its contents.
+// Files that cannot be stat'ed are reported with a negative size.
+func Sizes(content *ls.RepoContent) []FileSize {
+	sizes := make([]FileSize, 0, len(content.Files))
+	for _, relPath := range content.Files {
+		size := int64(-1)
+		if info, err := fs.Stat(content.FS, relPath); err != nil {
+			log.Warn("failed to stat file", "file", relPath, "error", err)
+		} else {
+			size = info.Size()
+		}
+		sizes = append(sizes, FileSize{Path: relPath, Size: size})
+	}
+	return sizes
 }
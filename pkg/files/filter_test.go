@@ -0,0 +1,51 @@
+package files
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFilterBySize(t *testing.T) {
+	repo := testContent(fstest.MapFS{
+		"empty.txt":  {},
+		"small.txt":  {Data: []byte("0123456789")},
+		"medium.txt": {Data: []byte(strings.Repeat("x", 100))},
+		"large.txt":  {Data: []byte(strings.Repeat("x", 1000))},
+	})
+
+	tests := []struct {
+		name     string
+		min, max int64
+		want     []string
+	}{
+		{"no limits", 0, -1, []string{"empty.txt", "large.txt", "medium.txt", "small.txt"}},
+		{"min", 100, -1, []string{"large.txt", "medium.txt"}},
+		{"max", 0, 100, []string{"empty.txt", "medium.txt", "small.txt"}},
+		{"max zero", 0, 0, []string{"empty.txt"}},
+		{"range", 10, 100, []string{"medium.txt", "small.txt"}},
+		{"empty range", 101, 999, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterBySize(repo, tt.min, tt.max)
+			if !slices.Equal(got.Files, tt.want) {
+				t.Errorf("FilterBySize(%d, %d) = %q, want %q", tt.min, tt.max, got.Files, tt.want)
+			}
+			if len(repo.Files) != 4 {
+				t.Errorf("FilterBySize modified the input files: %q", repo.Files)
+			}
+		})
+	}
+}
+
+func TestSizes(t *testing.T) {
+	repo := testContent(fstest.MapFS{"a.txt": {Data: []byte("abc")}})
+	repo.Files = append(repo.Files, "missing.txt")
+
+	want := []FileSize{{"a.txt", 3}, {"missing.txt", -1}}
+	if got := Sizes(repo); !slices.Equal(got, want) {
+		t.Errorf("Sizes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+package files
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/i-zaitsev/gitcat/pkg/internal/utils"
+	"github.com/i-zaitsev/gitcat/pkg/log"
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+// Names of the rules that detect generated and vendored files.
+const (
+	RuleVendorDir     = "vendor-dir"     // under a vendor/, third_party/ or node_modules/ directory
+	RuleGeneratedName = "generated-name" // named like the output of a code generator, e.g. x.pb.go
+	RuleLockfile      = "lockfile"       // a package manager lockfile, e.g. package-lock.json
+	RuleMinified      = "minified"       // a minified script or stylesheet
+	RuleHeader        = "header"         // starts with a "Code generated ... DO NOT EDIT." comment
+)
+
+var (
+	vendorDirs = []string{"vendor", "third_party", "node_modules"}
+
+	generatedSuffixes = []string{".pb.go", ".pb.gw.go", "_gen.go", "_generated.go", "_pb2.py", "_pb2_grpc.py"}
+
+	lockfiles = []string{
+		"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", "pnpm-lock.yaml", "bun.lockb",
+		"go.sum", "Cargo.lock", "Gemfile.lock", "composer.lock", "poetry.lock", "Pipfile.lock",
+		"uv.lock", "pubspec.lock", "mix.lock", "flake.lock",
+	}
+
+	minifiedExts = []string{".js", ".mjs", ".cjs", ".css"}
+
+	// generatedHeader matches the comment marking generated files in Go,
+	// which other generators have adopted, and the @generated tag.
+	generatedHeader = regexp.MustCompile(`(?m)^\W*(Code generated .*DO NOT EDIT\.?|@generated\b)`)
+)
+
+const (
+	// headSize is the size of the start of a file read for the header and minified rules.
+	headSize = 8 * 1024
+	// headerLines is the number of lines searched for a generated header.
+	headerLines = 20
+	// minifiedLineLength is the average line length above which a script or
+	// stylesheet filling the head is considered minified.
+	minifiedLineLength = 500
+)
+
+// Classify tags the generated and vendored files of the repository, as
+// detected by file names and by the start of their contents, and drops the
+// ones of the kinds selected by dropGenerated and dropVendored. Contents are
+// only read when generated files are dropped or tagged reports that the tags
+// are written out; otherwise only the names of the files are checked.
+// The number of files dropped by each rule is logged.
+func Classify(content *ls.RepoContent, dropGenerated, dropVendored, tagged bool) *ls.RepoContent {
+	var (
+		classified = content.WithFiles(nil)
+		kept       []string
+		dropped    = make(map[string]int)
+		sniff      = dropGenerated || tagged
+	)
+	for _, relPath := range content.Files {
+		tag, rule := classify(content.FS, relPath, sniff)
+		if rule == "" {
+			kept = append(kept, relPath)
+			continue
+		}
+		if tag == ls.TagGenerated && dropGenerated || tag == ls.TagVendored && dropVendored {
+			log.Debug("dropping file", "file", relPath, "tag", tag, "rule", rule)
+			dropped[rule]++
+			continue
+		}
+		log.Debug("tagging file", "file", relPath, "tag", tag, "rule", rule)
+		classified.Tag(relPath, tag)
+		kept = append(kept, relPath)
+	}
+
+	if len(dropped) > 0 {
+		var args []any
+		for _, rule := range []string{RuleVendorDir, RuleGeneratedName, RuleLockfile, RuleMinified, RuleHeader} {
+			if n := dropped[rule]; n > 0 {
+				args = append(args, rule, n)
+			}
+		}
+		log.Info("dropped generated and vendored files", args...)
+	}
+	classified.Files = kept
+	return classified
+}
+
+// classify returns the tag of a file and the rule that detected it,
+// or empty strings for files that look handwritten. The start of the file
+// is only read when sniff is set.
+func classify(fsys fs.FS, name string, sniff bool) (tag, rule string) {
+	dir, base := path.Split(name)
+	for _, d := range vendorDirs {
+		if strings.HasPrefix(dir, d+"/") || strings.Contains(dir, "/"+d+"/") {
+			return ls.TagVendored, RuleVendorDir
+		}
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return ls.TagGenerated, RuleGeneratedName
+		}
+	}
+	if slices.Contains(lockfiles, base) {
+		return ls.TagGenerated, RuleLockfile
+	}
+	ext := path.Ext(base)
+	if slices.Contains(minifiedExts, ext) && strings.HasSuffix(strings.TrimSuffix(base, ext), ".min") {
+		return ls.TagGenerated, RuleMinified
+	}
+	if !sniff {
+		return "", ""
+	}
+
+	head, err := readHead(fsys, name)
+	if err != nil {
+		log.Debug("failed to read file for classification", "file", name, "error", err)
+		return "", ""
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return "", ""
+	}
+	if slices.Contains(minifiedExts, ext) && len(head) == headSize &&
+		len(head)/(bytes.Count(head, []byte("\n"))+1) > minifiedLineLength {
+		return ls.TagGenerated, RuleMinified
+	}
+	lines := bytes.SplitN(head, []byte("\n"), headerLines+1)
+	if len(lines) > headerLines {
+		lines = lines[:headerLines]
+	}
+	if generatedHeader.Match(bytes.Join(lines, []byte("\n"))) {
+		return ls.TagGenerated, RuleHeader
+	}
+	return "", ""
+}
+
+// readHead reads up to headSize bytes from the start of a file.
+func readHead(fsys fs.FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.SilentClose(f)
+	head := make([]byte, headSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	return head[:n], nil
+}
@@ -0,0 +1,143 @@
+package files
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+func TestClassify(t *testing.T) {
+	minified := strings.Repeat("var a=1;", headSize/8)
+	longLines := strings.Repeat(strings.Repeat("x", 100)+"\n", headSize/100+1)
+
+	tests := []struct {
+		name      string
+		data      string
+		wantTag   string
+		wantRule  string
+		wantNamed bool // detected without reading the file
+	}{
+		{"vendor/github.com/x/y.go", "package y", ls.TagVendored, RuleVendorDir, true},
+		{"web/node_modules/lib/index.js", "", ls.TagVendored, RuleVendorDir, true},
+		{"third_party/lib/lib.c", "", ls.TagVendored, RuleVendorDir, true},
+		{"pkg/vendors/x.go", "package vendors", "", "", false},
+		{"vendor.go", "package main", "", "", false},
+		{"api/api.pb.go", "package api", ls.TagGenerated, RuleGeneratedName, true},
+		{"db/models_gen.go", "package db", ls.TagGenerated, RuleGeneratedName, true},
+		{"proto/x_pb2.py", "", ls.TagGenerated, RuleGeneratedName, true},
+		{"package-lock.json", "{}", ls.TagGenerated, RuleLockfile, true},
+		{"sub/go.sum", "", ls.TagGenerated, RuleLockfile, true},
+		{"Cargo.lock", "", ls.TagGenerated, RuleLockfile, true},
+		{"yarn.lock.bak", "", "", "", false},
+		{"dist/app.min.js", "var a;", ls.TagGenerated, RuleMinified, true},
+		{"css/site.min.css", "a{}", ls.TagGenerated, RuleMinified, true},
+		{"docs/min.js", "var a;", "", "", false},
+		{"dist/bundle.js", minified, ls.TagGenerated, RuleMinified, false},
+		{"dist/short.js", "var a=1;var b=2;", "", "", false},
+		{"dist/readable.js", longLines, "", "", false},
+		{"dist/bundle.txt", minified, "", "", false},
+		{"x/enum.go", "// Code generated by stringer; DO NOT EDIT.\n\npackage x\n", ls.TagGenerated, RuleHeader, false},
+		{"x/mock.go", "// Copyright\n\n// Code generated by MockGen. DO NOT EDIT.\n", ls.TagGenerated, RuleHeader, false},
+		{"x/schema.ts", "/* @generated */\nexport {}\n", ls.TagGenerated, RuleHeader, false},
+		{"x/late.go", strings.Repeat("//\n", headerLines) + "// Code generated by x. DO NOT EDIT.\n", "", "", false},
+		{"x/mention.go", "package x\n\nvar s = \"Code generated by x. DO NOT EDIT.\"\n", "", "", false},
+		{"x/binary.bin", "\x00// Code generated by x. DO NOT EDIT.\n", "", "", false},
+		{"main.go", "package main\n", "", "", false},
+	}
+	fsys := fstest.MapFS{}
+	for _, tt := range tests {
+		fsys[tt.name] = &fstest.MapFile{Data: []byte(tt.data)}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, rule := classify(fsys, tt.name, true)
+			if tag != tt.wantTag || rule != tt.wantRule {
+				t.Errorf("classify() = %q, %q, want %q, %q", tag, rule, tt.wantTag, tt.wantRule)
+			}
+			tag, rule = classify(fsys, tt.name, false)
+			if tt.wantNamed && (tag != tt.wantTag || rule != tt.wantRule) || !tt.wantNamed && rule != "" {
+				t.Errorf("classify() without reading = %q, %q", tag, rule)
+			}
+		})
+	}
+}
+
+func TestClassifyDrop(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":            {Data: []byte("package main\n")},
+		"enum.go":            {Data: []byte("// Code generated by stringer. DO NOT EDIT.\n")},
+		"go.sum":             {Data: []byte("")},
+		"vendor/lib/lib.go":  {Data: []byte("package lib\n")},
+		"vendor/lib/enum.go": {Data: []byte("// Code generated by stringer. DO NOT EDIT.\n")},
+	}
+	all := []string{"enum.go", "go.sum", "main.go", "vendor/lib/enum.go", "vendor/lib/lib.go"}
+
+	tests := []struct {
+		name                        string
+		dropGenerated, dropVendored bool
+		tagged                      bool
+		wantFiles                   []string
+		wantTagged                  map[string]string
+	}{
+		{
+			name:      "tag only",
+			tagged:    true,
+			wantFiles: all,
+			wantTagged: map[string]string{
+				"enum.go": ls.TagGenerated, "go.sum": ls.TagGenerated,
+				"vendor/lib/enum.go": ls.TagVendored, "vendor/lib/lib.go": ls.TagVendored,
+			},
+		},
+		{
+			name:      "names only",
+			wantFiles: all,
+			wantTagged: map[string]string{
+				"go.sum":             ls.TagGenerated,
+				"vendor/lib/enum.go": ls.TagVendored, "vendor/lib/lib.go": ls.TagVendored,
+			},
+		},
+		{
+			name:          "drop generated",
+			dropGenerated: true,
+			wantFiles:     []string{"main.go", "vendor/lib/enum.go", "vendor/lib/lib.go"},
+			wantTagged:    map[string]string{"vendor/lib/enum.go": ls.TagVendored, "vendor/lib/lib.go": ls.TagVendored},
+		},
+		{
+			name:         "drop vendored",
+			dropVendored: true,
+			tagged:       true,
+			wantFiles:    []string{"enum.go", "go.sum", "main.go"},
+			wantTagged:   map[string]string{"enum.go": ls.TagGenerated, "go.sum": ls.TagGenerated},
+		},
+		{
+			name:          "drop both",
+			dropGenerated: true,
+			dropVendored:  true,
+			wantFiles:     []string{"main.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := &ls.RepoContent{FS: fsys, Files: slices.Clone(all)}
+			got := Classify(content, tt.dropGenerated, tt.dropVendored, tt.tagged)
+			if !slices.Equal(got.Files, tt.wantFiles) {
+				t.Errorf("Classify() files = %q, want %q", got.Files, tt.wantFiles)
+			}
+			for _, f := range got.Files {
+				var want []string
+				if tag, ok := tt.wantTagged[f]; ok {
+					want = []string{tag}
+				}
+				if tags := got.Attr(f).Tags; !slices.Equal(tags, want) {
+					t.Errorf("tags of %s = %q, want %q", f, tags, want)
+				}
+			}
+			if len(content.Attrs) != 0 {
+				t.Errorf("Classify() tagged the original content: %v", content.Attrs)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitclone"
+	"github.com/i-zaitsev/gitcat/pkg/internal/memfs"
+	"github.com/i-zaitsev/gitcat/pkg/log"
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+// LFS decides what happens to Git LFS pointer files and implements flag.Value interface.
+type LFS string
+
+const (
+	LFSPlaceholder LFS = "placeholder" // replace the pointer with a note naming the object
+	LFSSkip        LFS = "skip"        // leave the file out
+	LFSFetch       LFS = "fetch"       // read the object with git lfs, downloading it if needed
+)
+
+// String returns the string representation of the mode.
+func (m *LFS) String() string {
+	if m == nil || *m == "" {
+		return string(LFSPlaceholder)
+	}
+	return string(*m)
+}
+
+// Set validates and sets the mode.
+func (m *LFS) Set(value string) error {
+	switch LFS(value) {
+	case LFSPlaceholder, LFSSkip, LFSFetch:
+		*m = LFS(value)
+		return nil
+	default:
+		return fmt.Errorf("invalid lfs mode %q: must be one of: placeholder, skip, fetch", value)
+	}
+}
+
+// maxPointerSize is the size limit of pointer files set by the Git LFS specification.
+const maxPointerSize = 1024
+
+// lfsVersion starts every pointer file; older clients wrote the hawser URL.
+var lfsVersion = regexp.MustCompile(`^version https://(git-lfs|hawser)\.github\.com/spec/v1\n`)
+
+// LFSPointer is the content of a Git LFS pointer file.
+type LFSPointer struct {
+	OID  string // object id, e.g. "sha256:4d7a..."
+	Size int64  // size of the object in bytes
+}
+
+// ParseLFSPointer parses a Git LFS pointer file. It reports false when
+// the data is not a pointer.
+func ParseLFSPointer(data []byte) (LFSPointer, bool) {
+	if len(data) > maxPointerSize || !lfsVersion.Match(data) || !bytes.HasSuffix(data, []byte("\n")) {
+		return LFSPointer{}, false
+	}
+	var p LFSPointer
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")[1:] {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok {
+			return LFSPointer{}, false
+		}
+		switch key {
+		case "oid":
+			p.OID = value
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return LFSPointer{}, false
+			}
+			p.Size = size
+		}
+	}
+	return p, strings.HasPrefix(p.OID, "sha256:")
+}
+
+// Placeholder returns the text written instead of the object of the pointer file at path.
+func (p LFSPointer) Placeholder(path string) string {
+	return fmt.Sprintf("[Git LFS object not included: path=%s oid=%s size=%d]\n", path, p.OID, p.Size)
+}
+
+// ResolveLFS finds the Git LFS pointer files of the repository and applies the mode:
+// pointers are replaced by a placeholder or by the fetched object, or left out.
+// The returned content reports the size of the objects rather than the pointers,
+// so that it can be filtered with FilterBySize. Objects are fetched when the
+// files are read, with the credentials in auth for the origin remote.
+func ResolveLFS(ctx context.Context, content *ls.RepoContent, mode LFS, auth gitclone.Auth) *ls.RepoContent {
+	if mode == LFSFetch && content.GitDir == "" {
+		log.Warn("cannot fetch Git LFS objects outside a git repository, using placeholders", "root", content.Root)
+		mode = LFSPlaceholder
+	} else if mode == LFSFetch {
+		if _, err := exec.LookPath("git-lfs"); err != nil {
+			log.Warn("git lfs is not installed, using placeholders for Git LFS objects")
+			mode = LFSPlaceholder
+		}
+	}
+
+	var (
+		kept    []string
+		objects = memfs.New()
+		found   int
+	)
+	for _, relPath := range content.Files {
+		data, pointer, ok := readLFSPointer(content.FS, relPath)
+		if !ok {
+			kept = append(kept, relPath)
+			continue
+		}
+		found++
+		log.Debug("found Git LFS pointer", "file", relPath, "oid", pointer.OID, "size", pointer.Size)
+		switch mode {
+		case LFSSkip:
+			continue
+		case LFSFetch:
+			objects.Add(relPath, pointer.Size, 0644, time.Time{}, func() ([]byte, error) {
+				return smudge(ctx, content.GitDir, relPath, data, auth)
+			})
+		default:
+			objects.Add(relPath, pointer.Size, 0644, time.Time{}, memfs.Bytes([]byte(pointer.Placeholder(relPath))))
+		}
+		kept = append(kept, relPath)
+	}
+	if found == 0 {
+		return content
+	}
+
+	log.Info("found Git LFS pointer files", "count", found, "mode", mode.String())
+	resolved := content.WithFiles(kept)
+	resolved.FS = memfs.Overlay(content.FS, objects)
+	return resolved
+}
+
+// readLFSPointer reads the file if it is small enough to be a pointer and parses it.
+func readLFSPointer(fsys fs.FS, name string) ([]byte, LFSPointer, bool) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxPointerSize {
+		return nil, LFSPointer{}, false
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, LFSPointer{}, false
+	}
+	pointer, ok := ParseLFSPointer(data)
+	return data, pointer, ok
+}
+
+// smudge reads the object of a pointer file with git lfs, which downloads
+// it from the remote of the repository unless it is already stored locally.
+func smudge(ctx context.Context, gitDir, name string, pointer []byte, auth gitclone.Auth) ([]byte, error) {
+	log.Debug("fetching Git LFS object", "file", name)
+	cmd := exec.CommandContext(ctx, "git", "lfs", "smudge", "--", name)
+	cmd.Dir = gitDir
+	cmd.Env = os.Environ()
+	if remote, err := gitclone.RemoteURL(ctx, gitDir); err == nil {
+		cmd.Env = append(cmd.Env, auth.Env(remote)...)
+	}
+	cmd.Stdin = bytes.NewReader(pointer)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git lfs smudge: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
@@ -0,0 +1,103 @@
+package files
+
+import (
+	"context"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitclone"
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+const (
+	lfsOID     = "sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+	lfsPointer = "version https://git-lfs.github.com/spec/v1\noid " + lfsOID + "\nsize 12345\n"
+)
+
+func TestParseLFSPointer(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		want   LFSPointer
+		wantOK bool
+	}{
+		{"pointer", lfsPointer, LFSPointer{OID: lfsOID, Size: 12345}, true},
+		{
+			"hawser version",
+			"version https://hawser.github.com/spec/v1\noid " + lfsOID + "\nsize 1\n",
+			LFSPointer{OID: lfsOID, Size: 1},
+			true,
+		},
+		{
+			"extension keys",
+			"version https://git-lfs.github.com/spec/v1\next-0-foo sha256:00\noid " + lfsOID + "\nsize 0\n",
+			LFSPointer{OID: lfsOID},
+			true,
+		},
+		{"no trailing newline", lfsPointer[:len(lfsPointer)-1], LFSPointer{}, false},
+		{"no oid", "version https://git-lfs.github.com/spec/v1\nsize 10\n", LFSPointer{}, false},
+		{"other hash", "version https://git-lfs.github.com/spec/v1\noid sha1:abc\nsize 10\n", LFSPointer{}, false},
+		{"negative size", "version https://git-lfs.github.com/spec/v1\noid " + lfsOID + "\nsize -1\n", LFSPointer{}, false},
+		{"invalid size", "version https://git-lfs.github.com/spec/v1\noid " + lfsOID + "\nsize ten\n", LFSPointer{}, false},
+		{"line without value", "version https://git-lfs.github.com/spec/v1\noid " + lfsOID + "\nsize\n", LFSPointer{}, false},
+		{"other version", "version https://example.com/spec/v1\noid " + lfsOID + "\nsize 10\n", LFSPointer{}, false},
+		{"version not first", "oid " + lfsOID + "\nversion https://git-lfs.github.com/spec/v1\nsize 10\n", LFSPointer{}, false},
+		{"text", "package main\n", LFSPointer{}, false},
+		{"empty", "", LFSPointer{}, false},
+		{"too large", lfsPointer + "x " + string(make([]byte, maxPointerSize)) + "\n", LFSPointer{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseLFSPointer([]byte(tt.data))
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("ParseLFSPointer() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestResolveLFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":          {Data: []byte("package main\n")},
+		"assets/model.bin": {Data: []byte(lfsPointer)},
+	}
+	content := &ls.RepoContent{FS: fsys, Files: []string{"assets/model.bin", "main.go"}}
+
+	tests := []struct {
+		mode      LFS
+		wantFiles []string
+		wantData  string
+	}{
+		{LFSPlaceholder, []string{"assets/model.bin", "main.go"}, LFSPointer{OID: lfsOID, Size: 12345}.Placeholder("assets/model.bin")},
+		{LFSSkip, []string{"main.go"}, ""},
+		// Without a git directory the objects cannot be fetched.
+		{LFSFetch, []string{"assets/model.bin", "main.go"}, LFSPointer{OID: lfsOID, Size: 12345}.Placeholder("assets/model.bin")},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			got := ResolveLFS(context.Background(), content, tt.mode, gitclone.Auth{})
+			if !slices.Equal(got.Files, tt.wantFiles) {
+				t.Fatalf("ResolveLFS() files = %q, want %q", got.Files, tt.wantFiles)
+			}
+			if tt.wantData == "" {
+				return
+			}
+			data, err := fs.ReadFile(got.FS, "assets/model.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.wantData {
+				t.Errorf("content = %q, want %q", data, tt.wantData)
+			}
+			info, err := fs.Stat(got.FS, "assets/model.bin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != 12345 {
+				t.Errorf("size = %d, want the object size 12345", info.Size())
+			}
+		})
+	}
+}
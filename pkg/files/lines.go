@@ -0,0 +1,157 @@
+package files
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LineRange represents an inclusive, 1-based range of lines.
+// End is 0 when the range extends to the end of the file.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// IsZero reports whether the range is unset.
+func (r *LineRange) IsZero() bool {
+	return r == nil || (r.Start == 0 && r.End == 0)
+}
+
+// Contains reports whether the given 1-based line number is within the range.
+func (r *LineRange) Contains(line int) bool {
+	return line >= r.Start && (r.End == 0 || line <= r.End)
+}
+
+func (r *LineRange) String() string {
+	if r.IsZero() {
+		return ""
+	}
+	if r.End == 0 {
+		return fmt.Sprintf("%d-", r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// Set parses a range in the form "100-250", "100-" or "100".
+func (r *LineRange) Set(value string) error {
+	if value == "" {
+		*r = LineRange{}
+		return nil
+	}
+
+	from, to, isRange := strings.Cut(value, "-")
+	start, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil || start < 1 {
+		return fmt.Errorf("invalid line range %q: start must be a positive integer", value)
+	}
+
+	end := start
+	if isRange {
+		end = 0
+		if to = strings.TrimSpace(to); to != "" {
+			if end, err = strconv.Atoi(to); err != nil || end < start {
+				return fmt.Errorf("invalid line range %q: end must be an integer not less than start", value)
+			}
+		}
+	}
+
+	*r = LineRange{Start: start, End: end}
+	return nil
+}
+
+// Sample represents the number of lines kept from both ends of a file.
+type Sample struct {
+	Head int
+	Tail int
+}
+
+func (s *Sample) String() string {
+	if s == nil || (s.Head == 0 && s.Tail == 0) {
+		return ""
+	}
+	return fmt.Sprintf("head=%d,tail=%d", s.Head, s.Tail)
+}
+
+// Set parses a sample in the form "head=40,tail=20".
+func (s *Sample) Set(value string) error {
+	*s = Sample{}
+	for _, part := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return fmt.Errorf("invalid sample %q: expected key=value pairs", value)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid sample %q: %s must be a non-negative integer", value, key)
+		}
+		switch strings.TrimSpace(key) {
+		case "head":
+			s.Head = n
+		case "tail":
+			s.Tail = n
+		default:
+			return fmt.Errorf("invalid sample %q: unknown key %q (must be head or tail)", value, key)
+		}
+	}
+	return nil
+}
+
+// Window selects which lines of a file are kept.
+// The zero value keeps every line.
+type Window struct {
+	Head  int       // keep the first Head lines
+	Tail  int       // keep the last Tail lines
+	Range LineRange // keep an explicit range, takes precedence over Head and Tail
+}
+
+// Lines selects the window applied to each file read by Cat.
+// Paths override the default window for files at or under the given paths.
+type Lines struct {
+	Window
+	Paths map[string]LineRange
+}
+
+// For returns the window to apply to the given file path.
+// When several paths contain the file, the longest one applies.
+func (l *Lines) For(path string) Window {
+	var (
+		match string
+		found bool
+		r     LineRange
+	)
+	for prefix, pr := range l.Paths {
+		if (path == prefix || strings.HasPrefix(path, prefix+"/")) && (!found || len(prefix) > len(match)) {
+			match, found, r = prefix, true, pr
+		}
+	}
+	if found {
+		return Window{Range: r}
+	}
+	return l.Window
+}
+
+// SplitPathRanges separates "path:100-250" suffixes from the given paths.
+// It returns the plain paths and the line ranges keyed by path.
+func SplitPathRanges(paths []string) ([]string, map[string]LineRange, error) {
+	plain := make([]string, 0, len(paths))
+	var ranges map[string]LineRange
+	for _, p := range paths {
+		name, spec, ok := strings.Cut(p, ":")
+		if !ok {
+			plain = append(plain, p)
+			continue
+		}
+		var r LineRange
+		if err := r.Set(spec); err != nil {
+			return nil, nil, fmt.Errorf("path %s: %w", name, err)
+		}
+		if ranges == nil {
+			ranges = make(map[string]LineRange)
+		}
+		name = strings.TrimSuffix(name, "/")
+		ranges[name] = r
+		plain = append(plain, name)
+	}
+	return plain, ranges, nil
+}
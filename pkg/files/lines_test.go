@@ -0,0 +1,88 @@
+package files
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLinesFor(t *testing.T) {
+	lines := Lines{
+		Window: Window{Head: 10},
+		Paths: map[string]LineRange{
+			"pkg":            {Start: 1, End: 5},
+			"pkg/files":      {Start: 10, End: 20},
+			"pkg/files/a.go": {Start: 3},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want Window
+	}{
+		{"main.go", Window{Head: 10}},
+		{"pkg/ls/ls.go", Window{Range: LineRange{Start: 1, End: 5}}},
+		{"pkg/files/cat.go", Window{Range: LineRange{Start: 10, End: 20}}},
+		{"pkg/files/a.go", Window{Range: LineRange{Start: 3}}},
+		{"pkgs/x.go", Window{Head: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			for range 20 {
+				if got := lines.For(tt.path); got != tt.want {
+					t.Fatalf("For(%q) = %+v, want %+v", tt.path, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	input := "1\n2\n3\n4\n5\n6\n"
+
+	tests := []struct {
+		name   string
+		window Window
+		want   []string
+	}{
+		{"all", Window{}, []string{"1\n", "2\n", "3\n", "4\n", "5\n", "6\n"}},
+		{"head", Window{Head: 2}, []string{"1\n", "2\n"}},
+		{"tail", Window{Tail: 2}, []string{"5\n", "6\n"}},
+		{"head and tail", Window{Head: 1, Tail: 1}, []string{"1\n", "... 4 lines omitted ...\n", "6\n"}},
+		{"range", Window{Range: LineRange{Start: 2, End: 3}}, []string{"2\n", "3\n"}},
+		{"open range", Window{Range: LineRange{Start: 5}}, []string{"5\n", "6\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readLines(context.Background(), strings.NewReader(input), tt.window)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("readLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLinesLong(t *testing.T) {
+	defer func(size int) { maxLineSize = size }(maxLineSize)
+	maxLineSize = 1024
+
+	long := strings.Repeat("x", maxLineSize-1)
+	got, err := readLines(context.Background(), strings.NewReader(long+"\nend\n"), Window{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != long+"\n" {
+		t.Errorf("readLines() returned %d lines, want the long line and the last one", len(got))
+	}
+
+	_, err = readLines(context.Background(), strings.NewReader(strings.Repeat("x", maxLineSize+1)), Window{})
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("readLines() error = %v, want %v", err, bufio.ErrTooLong)
+	}
+}
@@ -27,14 +27,13 @@ func DiscoverExt(content *ls.RepoContent) []string {
 // MatchExt checks if a file extension matches the given pattern.
 // It returns a new RepoContent with matching files only.
 func MatchExt(content *ls.RepoContent, ext ...string) *ls.RepoContent {
-	var matched ls.RepoContent
-	matched.Root = content.Root
+	var matched []string
 	for _, filename := range content.Files {
 		for _, e := range ext {
 			if filepath.Ext(filename) == e {
-				matched.Files = append(matched.Files, filename)
+				matched = append(matched, filename)
 			}
 		}
 	}
-	return &matched
+	return content.WithFiles(matched)
 }
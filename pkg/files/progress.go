@@ -0,0 +1,46 @@
+package files
+
+import (
+	"fmt"
+
+	"github.com/i-zaitsev/gitcat/pkg/log"
+)
+
+// minProgressFiles is the number of files from which reading progress is reported.
+const minProgressFiles = 200
+
+// Progress reports how many of the selected files have been read.
+// It is silent for repositories with fewer than minProgressFiles files.
+type Progress struct {
+	total    int
+	done     int
+	bytes    int64
+	progress *log.Progress
+}
+
+// NewProgress creates a reporter for reading total files.
+func NewProgress(total int) *Progress {
+	p := &Progress{total: total}
+	if total >= minProgressFiles {
+		p.progress = log.NewProgress("reading files")
+	}
+	return p
+}
+
+// Add records that the given number of files, of the given size in bytes, have been read.
+func (p *Progress) Add(files int, bytes int64) {
+	p.done += files
+	p.bytes += bytes
+	if p.progress == nil {
+		return
+	}
+	p.progress.Update(fmt.Sprintf("%d/%d files, %d bytes", p.done, p.total, p.bytes),
+		"files", p.done, "total", p.total, "bytes", p.bytes)
+}
+
+// Done clears the progress line.
+func (p *Progress) Done() {
+	if p.progress != nil {
+		p.progress.Done()
+	}
+}
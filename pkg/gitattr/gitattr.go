@@ -0,0 +1,210 @@
+// Package gitattr reads the attributes assigned to paths by .gitattributes
+// files, following the precedence rules of git: later lines override earlier
+// ones, and files in subdirectories override the ones above them.
+package gitattr
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FileName is the name of the files holding attributes.
+const FileName = ".gitattributes"
+
+// Attributes of linguist, the library GitHub classifies repository files
+// with, and of git archive.
+const (
+	Generated     = "linguist-generated"
+	Vendored      = "linguist-vendored"
+	Documentation = "linguist-documentation"
+	ExportIgnore  = "export-ignore"
+)
+
+// Attributes holds the rules read from the .gitattributes files of a tree.
+// The zero value has no rules.
+type Attributes struct {
+	rules  []rule
+	loaded map[string]bool
+}
+
+// rule assigns attributes to the paths under dir matching a pattern.
+type rule struct {
+	dir     string
+	pattern *regexp.Regexp
+	base    bool // the pattern has no slash and matches the base name at any depth
+	attrs   map[string]string
+}
+
+// Load reads the .gitattributes file of dir in fsys, if there is one.
+// The files of parent directories must be loaded before their children;
+// loading a directory again has no effect.
+func (a *Attributes) Load(fsys fs.FS, dir string) error {
+	if a.loaded[dir] {
+		return nil
+	}
+	if a.loaded == nil {
+		a.loaded = make(map[string]bool)
+	}
+	a.loaded[dir] = true
+
+	name := path.Join(dir, FileName)
+	data, err := fs.ReadFile(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		r, ok, err := parseLine(dir, scanner.Text())
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		if ok {
+			a.rules = append(a.rules, r)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseLine parses a line of pattern and attributes. Comments, blank lines,
+// macro definitions, and negative and directory patterns, which git ignores,
+// are skipped.
+func parseLine(dir, line string) (rule, bool, error) {
+	line = strings.TrimLeft(line, " \t")
+	if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[attr]") {
+		return rule{}, false, nil
+	}
+	pattern, rest := splitPattern(line)
+	fields := strings.Fields(rest)
+	if pattern == "" || len(fields) == 0 {
+		return rule{}, false, nil
+	}
+	if strings.HasPrefix(pattern, "!") || strings.HasSuffix(pattern, "/") {
+		return rule{}, false, nil
+	}
+
+	re, err := regexp.Compile("^" + globRegexp(strings.TrimPrefix(pattern, "/")) + "$")
+	if err != nil {
+		return rule{}, false, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+	r := rule{
+		dir:     dir,
+		pattern: re,
+		base:    !strings.Contains(pattern, "/"),
+		attrs:   make(map[string]string, len(fields)),
+	}
+	for _, attr := range fields {
+		switch {
+		case strings.HasPrefix(attr, "-"):
+			r.attrs[attr[1:]] = "false"
+		case strings.HasPrefix(attr, "!"):
+			r.attrs[attr[1:]] = ""
+		default:
+			key, value, ok := strings.Cut(attr, "=")
+			if !ok {
+				value = "true"
+			}
+			r.attrs[key] = value
+		}
+	}
+	return r, true, nil
+}
+
+// splitPattern splits a line into its pattern and the attributes after it.
+// Like git, a pattern starting with a double quote is unquoted as a C-style
+// string, which allows spaces in it, and is read as it is when the quoting
+// is invalid.
+func splitPattern(line string) (pattern, rest string) {
+	if strings.HasPrefix(line, `"`) {
+		if quoted, err := strconv.QuotedPrefix(line); err == nil {
+			if pattern, err := strconv.Unquote(quoted); err == nil {
+				return pattern, line[len(quoted):]
+			}
+		}
+	}
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		return line[:i], line[i:]
+	}
+	return line, ""
+}
+
+// globRegexp translates a gitignore-style pattern into a regular expression:
+// * and ? do not match slashes, while a leading **/, a trailing /** and
+// a /**/ in between match any number of directories.
+func globRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case pattern[i:] == "**" && i > 0 && pattern[i-1] == '/':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// Get returns the value of attr for the slash-separated path name: "true"
+// when it is set, "false" when it is unset, and "" when it is unspecified.
+func (a *Attributes) Get(name, attr string) string {
+	value := ""
+	for _, r := range a.rules {
+		v, ok := r.attrs[attr]
+		if ok && r.matches(name) {
+			value = v
+		}
+	}
+	return value
+}
+
+// IsSet reports whether attr is set for the path name.
+func (a *Attributes) IsSet(name, attr string) bool {
+	return a.Get(name, attr) == "true"
+}
+
+// matches reports whether the rule applies to the path name.
+func (r rule) matches(name string) bool {
+	rel := name
+	if r.dir != "." {
+		var ok bool
+		if rel, ok = strings.CutPrefix(name, r.dir+"/"); !ok {
+			return false
+		}
+	}
+	if r.base {
+		rel = path.Base(rel)
+	}
+	return r.pattern.MatchString(rel)
+}
@@ -0,0 +1,140 @@
+package gitattr
+
+import (
+	"regexp"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGlobRegexp(t *testing.T) {
+	// Examples from the PATTERN FORMAT section of gitignore(5), which
+	// .gitattributes patterns follow.
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{"*.c", []string{"a.c", ".c"}, []string{"a.h", "dir/a.c", "a.cc"}},
+		{"foo/*", []string{"foo/test.json", "foo/bar"}, []string{"foo/bar/hello.c", "foo", "x/foo/bar"}},
+		{"doc/frotz", []string{"doc/frotz"}, []string{"a/doc/frotz", "doc/frotz/x"}},
+		{"**/foo", []string{"foo", "a/foo", "a/b/foo"}, []string{"foox", "a/xfoo"}},
+		{"**/foo/bar", []string{"foo/bar", "a/foo/bar", "a/b/foo/bar"}, []string{"foo/baz", "afoo/bar"}},
+		{"abc/**", []string{"abc/x", "abc/x/y"}, []string{"abc", "xabc/x"}},
+		{"a/**/b", []string{"a/b", "a/x/b", "a/x/y/b"}, []string{"a/xb", "ab", "b/a/b"}},
+		{"a**b", []string{"ab", "axyb"}, []string{"ax/yb"}},
+		{"?.txt", []string{"a.txt", "é.txt"}, []string{"ab.txt", "/.txt", ".txt"}},
+		{"café/*.md", []string{"café/a.md"}, []string{"cafe/a.md", "cafÃ©/a.md"}},
+		{"[abc].go", []string{"a.go", "c.go"}, []string{"d.go", "ab.go"}},
+		{"[a-c]x", []string{"bx"}, []string{"dx"}},
+		{"[!a]x", []string{"bx"}, []string{"ax"}},
+		{`\*.md`, []string{"*.md"}, []string{"a.md"}},
+		{`\?`, []string{"?"}, []string{"a"}},
+		{"a[b", []string{"a[b"}, []string{"ab"}},
+		{"v1.0+build", []string{"v1.0+build"}, []string{"v1x0build"}},
+		{"dist/*.min.js", []string{"dist/app.min.js"}, []string{"dist/sub/app.min.js", "dist/appxminxjs"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			re := regexp.MustCompile("^" + globRegexp(tt.pattern) + "$")
+			for _, name := range tt.match {
+				if !re.MatchString(name) {
+					t.Errorf("%q does not match %q", tt.pattern, name)
+				}
+			}
+			for _, name := range tt.noMatch {
+				if re.MatchString(name) {
+					t.Errorf("%q matches %q", tt.pattern, name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line        string
+		wantPattern string
+		wantAttrs   map[string]string
+	}{
+		{"*.go text", "*.go", map[string]string{"text": "true"}},
+		{"  *.go\t-diff  !merge eol=lf", "*.go", map[string]string{"diff": "false", "merge": "", "eol": "lf"}},
+		{`"my file.txt" linguist-generated`, "my file.txt", map[string]string{Generated: "true"}},
+		{`"tab\there.txt" -text`, "tab\there.txt", map[string]string{"text": "false"}},
+		{`"caf\303\251.md" linguist-documentation`, "café.md", map[string]string{Documentation: "true"}},
+		{`"quote\".txt" binary`, `quote".txt`, map[string]string{"binary": "true"}},
+		{`"unterminated binary`, `"unterminated`, map[string]string{"binary": "true"}},
+		{"# comment text", "", nil},
+		{"  # indented comment", "", nil},
+		{"[attr]binary -diff -merge -text", "", nil},
+		{"*.go", "", nil},
+		{"", "", nil},
+		{"!*.go text", "", nil},
+		{"docs/ linguist-documentation", "", nil},
+		{`"" text`, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			r, ok, err := parseLine(".", tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != (tt.wantAttrs != nil) {
+				t.Fatalf("parseLine() ok = %v, want %v", ok, tt.wantAttrs != nil)
+			}
+			if !ok {
+				return
+			}
+			if !r.pattern.MatchString(tt.wantPattern) {
+				t.Errorf("pattern %s does not match %q", r.pattern, tt.wantPattern)
+			}
+			if len(r.attrs) != len(tt.wantAttrs) {
+				t.Errorf("attrs = %v, want %v", r.attrs, tt.wantAttrs)
+			}
+			for k, v := range tt.wantAttrs {
+				if r.attrs[k] != v {
+					t.Errorf("attr %s = %q, want %q", k, r.attrs[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestAttributesGet(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitattributes": {Data: []byte(
+			"*.pb.go linguist-generated\n" +
+				"/vendor/** linguist-vendored\n" +
+				"\"docs/user guide.md\" linguist-documentation\n" +
+				"*.txt -linguist-generated\n" +
+				"*.txt linguist-generated=maybe\n")},
+		"api/.gitattributes": {Data: []byte("*.pb.go -linguist-generated\nv1/*.go export-ignore\n")},
+	}
+	var attrs Attributes
+	for _, dir := range []string{".", "api", "api"} {
+		if err := attrs.Load(fsys, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name, attr, want string
+	}{
+		{"x.pb.go", Generated, "true"},
+		{"pkg/x.pb.go", Generated, "true"},
+		{"api/x.pb.go", Generated, "false"},
+		{"api/v1/x.pb.go", Generated, "false"},
+		{"vendor/lib/a.go", Vendored, "true"},
+		{"pkg/vendor/a.go", Vendored, ""},
+		{"docs/user guide.md", Documentation, "true"},
+		{"docs/user", Documentation, ""},
+		{"notes.txt", Generated, "maybe"},
+		{"api/v1/a.go", ExportIgnore, "true"},
+		{"api/v1/sub/a.go", ExportIgnore, ""},
+		{"v1/a.go", ExportIgnore, ""},
+	}
+	for _, tt := range tests {
+		if got := attrs.Get(tt.name, tt.attr); got != tt.want {
+			t.Errorf("Get(%q, %q) = %q, want %q", tt.name, tt.attr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package gitcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitclone"
+	"github.com/i-zaitsev/gitcat/pkg/gitpath"
+	"github.com/i-zaitsev/gitcat/pkg/log"
+)
+
+const (
+	mirrorSuffix = ".git"
+	metaSuffix   = ".json"
+)
+
+// Cache stores bare mirrors of remote repositories keyed by their normalized URL.
+// Mirrors are fetched incrementally on reuse, and the least recently used ones
+// are evicted when the total size exceeds MaxSize.
+type Cache struct {
+	Dir     string
+	MaxSize int64 // in bytes, 0 for no limit
+}
+
+// Entry describes a cached mirror.
+type Entry struct {
+	URL      string    `json:"url"`
+	LastUsed time.Time `json:"last_used"`
+	Key      string    `json:"-"`
+	Size     int64     `json:"-"`
+}
+
+// DefaultDir returns the cache location, $XDG_CACHE_HOME/gitcat on Linux.
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "gitcat-cache")
+	}
+	return filepath.Join(dir, "gitcat")
+}
+
+func New(dir string, maxSize int64) *Cache {
+	return &Cache{Dir: dir, MaxSize: maxSize}
+}
+
+// Key returns the cache key of the repository URL.
+func Key(url string) string {
+	normalized := gitpath.Normalize(url)
+	sum := sha256.Sum256([]byte(normalized))
+	name := filepath.Base(normalized)
+	return name + "-" + hex.EncodeToString(sum[:8])
+}
+
+// keyHash matches the hash ending the keys returned by Key.
+var keyHash = regexp.MustCompile(`.-[0-9a-f]{16}$`)
+
+// isKey reports whether the name has the form of a cache key.
+func isKey(name string) bool {
+	return keyHash.MatchString(name)
+}
+
+// Mirror returns the path of an up-to-date bare mirror of the repository,
+// cloning it on first use and fetching new objects on reuse. The mirror is
+// locked against concurrent updates and eviction until the returned function
+// is called, which must happen once the mirror has been cloned from.
+func (c *Cache) Mirror(ctx context.Context, repoUrl *gitpath.GitPath, auth gitclone.Auth) (string, func(), error) {
+	key := Key(repoUrl.CloneURL())
+	dir := filepath.Join(c.Dir, key+mirrorSuffix)
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	unlock, err := c.lock(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if _, err := os.Stat(dir); err == nil {
+		log.Info("fetching cached mirror", "url", repoUrl.Redacted(), "dir", dir)
+		if err := gitclone.Fetch(ctx, dir, auth); err != nil {
+			unlock()
+			return "", nil, fmt.Errorf("failed to fetch cached mirror: %w", err)
+		}
+	} else {
+		log.Info("creating cached mirror", "url", repoUrl.Redacted(), "dir", dir)
+		if err := gitclone.Mirror(ctx, repoUrl, dir, auth); err != nil {
+			_ = os.RemoveAll(dir)
+			unlock()
+			return "", nil, fmt.Errorf("failed to create cached mirror: %w", err)
+		}
+	}
+
+	entry := Entry{URL: repoUrl.CloneURL(), LastUsed: time.Now()}
+	if err := c.writeMeta(key, entry); err != nil {
+		unlock()
+		return "", nil, err
+	}
+
+	if _, err := c.evict(key); err != nil {
+		log.Warn("failed to evict cache entries", "error", err)
+	}
+	return dir, unlock, nil
+}
+
+// List returns the cached mirrors, most recently used first.
+func (c *Cache) List() ([]Entry, error) {
+	dirEntries, err := os.ReadDir(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var entries []Entry
+	for _, d := range dirEntries {
+		key, ok := strings.CutSuffix(d.Name(), mirrorSuffix)
+		if !ok || !d.IsDir() || !isKey(key) {
+			continue
+		}
+		entry := c.readMeta(key)
+		entry.Key = key
+		entry.Size = dirSize(filepath.Join(c.Dir, d.Name()))
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Prune evicts the least recently used mirrors until the cache fits in MaxSize.
+func (c *Cache) Prune() ([]Entry, error) {
+	return c.evict("")
+}
+
+// Clear removes the cached mirrors that are not in use, with their metadata,
+// and then the cache directory if nothing else is left in it. Files that were
+// not created by the cache are kept.
+func (c *Cache) Clear() error {
+	log.Info("clearing cache", "dir", c.Dir)
+	dirEntries, err := os.ReadDir(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	keys := make(map[string]bool)
+	for _, d := range dirEntries {
+		for _, suffix := range []string{mirrorSuffix, metaSuffix, lockSuffix} {
+			if key, ok := strings.CutSuffix(d.Name(), suffix); ok && isKey(key) {
+				keys[key] = true
+			}
+		}
+	}
+	for key := range keys {
+		f, ok, err := c.tryLock(key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Warn("skipping cached mirror in use", "key", key)
+			continue
+		}
+		err = c.remove(key)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(c.Dir); err != nil {
+		log.Debug("keeping cache directory", "dir", c.Dir, "error", err)
+	}
+	return nil
+}
+
+// evict removes the least recently used entries, never the one with the keep key
+// nor the ones in use, until the total size is within MaxSize. It returns the
+// removed entries.
+func (c *Cache) evict(keep string) ([]Entry, error) {
+	if c.MaxSize <= 0 {
+		return nil, nil
+	}
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+
+	var removed []Entry
+	for i := len(entries) - 1; i >= 0 && total > c.MaxSize; i-- {
+		e := entries[i]
+		if e.Key == keep {
+			continue
+		}
+		f, ok, err := c.tryLock(e.Key)
+		if err != nil {
+			return removed, err
+		}
+		if !ok {
+			log.Debug("not evicting cached mirror in use", "url", e.URL)
+			continue
+		}
+		log.Info("evicting cached mirror", "url", e.URL, "size", e.Size)
+		err = c.remove(e.Key)
+		_ = f.Close()
+		if err != nil {
+			return removed, err
+		}
+		total -= e.Size
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
+
+// remove deletes the mirror, metadata and lock file of an entry.
+// The caller must hold the lock of the entry.
+func (c *Cache) remove(key string) error {
+	if err := os.RemoveAll(filepath.Join(c.Dir, key+mirrorSuffix)); err != nil {
+		return fmt.Errorf("failed to remove cached mirror: %w", err)
+	}
+	_ = os.Remove(filepath.Join(c.Dir, key+metaSuffix))
+	_ = os.Remove(filepath.Join(c.Dir, key+lockSuffix))
+	return nil
+}
+
+func (c *Cache) writeMeta(key string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(c.Dir, key+metaSuffix), data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache metadata: %w", err)
+	}
+	return nil
+}
+
+// readMeta returns the stored metadata of an entry. Entries without readable
+// metadata are reported with a zero LastUsed, so they are evicted first.
+func (c *Cache) readMeta(key string) Entry {
+	var entry Entry
+	data, err := os.ReadFile(filepath.Join(c.Dir, key+metaSuffix))
+	if err != nil {
+		return entry
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.Warn("invalid cache metadata", "key", key, "error", err)
+	}
+	return entry
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
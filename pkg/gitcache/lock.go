@@ -0,0 +1,70 @@
+package gitcache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/i-zaitsev/gitcat/pkg/log"
+)
+
+const (
+	lockSuffix = ".lock"
+	// lockPoll is the interval between attempts to take a lock held by another process.
+	lockPoll = 100 * time.Millisecond
+)
+
+// lock takes the lock of the entry with the key, waiting until the processes
+// or goroutines holding it release it or ctx is done. The returned function
+// releases the lock.
+func (c *Cache) lock(ctx context.Context, key string) (func(), error) {
+	waiting := false
+	for {
+		f, ok, err := c.tryLock(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return func() { _ = f.Close() }, nil
+		}
+		if !waiting {
+			log.Info("waiting for the cached mirror to be released", "key", key)
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockPoll):
+		}
+	}
+}
+
+// tryLock takes the lock of the entry with the key if nothing else holds it.
+// The lock is held until the returned file is closed.
+func (c *Cache) tryLock(key string) (*os.File, bool, error) {
+	name := filepath.Join(c.Dir, key+lockSuffix)
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open cache lock: %w", err)
+	}
+	ok, err := lockFile(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, false, fmt.Errorf("failed to lock cache entry: %w", err)
+	}
+	if !ok {
+		_ = f.Close()
+		return nil, false, nil
+	}
+
+	// The entry may have been removed, with its lock file, while waiting.
+	locked, err := f.Stat()
+	current, statErr := os.Stat(name)
+	if err != nil || statErr != nil || !os.SameFile(locked, current) {
+		_ = f.Close()
+		return nil, false, nil
+	}
+	return f, true, nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package gitcache
+
+import "os"
+
+// lockFile always succeeds: file locks are only supported on Unix systems,
+// where concurrent runs sharing a cache wait for each other.
+func lockFile(*os.File) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,228 @@
+package gitclone
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitpath"
+	"github.com/i-zaitsev/gitcat/pkg/log"
+)
+
+// Auth holds the credentials passed to git through its environment,
+// so they never appear in process arguments or remote URLs.
+type Auth struct {
+	Token            string // HTTPS token, sent as a basic authorization header
+	SSHKey           string // private key file used for SSH remotes
+	KnownHosts       string // known_hosts file used for SSH remotes
+	InsecureHostKeys bool   // disable strict host key checking for SSH remotes
+
+	skipLFS bool // check out Git LFS pointer files without downloading the objects
+}
+
+// TokenEnvVars lists the environment variables checked for an HTTPS token.
+// GITCAT_TOKEN applies to any host; the others only to their own hosts
+// and to the hosts listed in TokenHostsEnvVar.
+var TokenEnvVars = []string{"GITCAT_TOKEN", "GITHUB_TOKEN", "GITLAB_TOKEN"}
+
+// TokenHostsEnvVar names the environment variable with a comma-separated
+// list of self-hosted hosts that receive GITHUB_TOKEN or GITLAB_TOKEN.
+const TokenHostsEnvVar = "GITCAT_TOKEN_HOSTS"
+
+// ResolveToken returns the HTTPS token for the repository URL.
+// A token file takes precedence over the environment variables.
+// Tokens are never sent over plain HTTP: for http:// URLs, a warning
+// is logged and no token is returned.
+func ResolveToken(repoUrl *gitpath.GitPath, tokenFile string) (string, error) {
+	token, err := findToken(repoUrl, tokenFile)
+	if err != nil || token == "" {
+		return token, err
+	}
+	if isPlainHTTP(repoUrl.Path) {
+		log.Warn("not sending the token over unencrypted HTTP, cloning without credentials", "url", repoUrl.Redacted())
+		return "", nil
+	}
+	return token, nil
+}
+
+// findToken reads the token from the token file or the environment variables.
+func findToken(repoUrl *gitpath.GitPath, tokenFile string) (string, error) {
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	host := urlHost(repoUrl.Path)
+	for _, name := range TokenEnvVars {
+		token := os.Getenv(name)
+		if token == "" {
+			continue
+		}
+		switch {
+		case name == "GITHUB_TOKEN" && host != "github.com" && !isTokenHost(host):
+		case name == "GITLAB_TOKEN" && host != "gitlab.com" && !isTokenHost(host):
+		default:
+			return token, nil
+		}
+	}
+	return "", nil
+}
+
+// isTokenHost reports whether the user listed the host in TokenHostsEnvVar.
+func isTokenHost(host string) bool {
+	for h := range strings.SplitSeq(os.Getenv(TokenHostsEnvVar), ",") {
+		if h = strings.TrimSpace(h); h != "" && strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Env returns the environment variables that configure git to send the
+// credentials to the host of the given URL: an authorization header for
+// HTTPS remotes, and an SSH command for the other remotes unless one is
+// already set in the environment. Plain HTTP remotes get no credentials.
+func (a Auth) Env(url string) []string {
+	if isPlainHTTP(url) {
+		return nil
+	}
+	if !isHTTP(url) {
+		// A GIT_SSH_COMMAND of the user is kept unless a key is given explicitly.
+		if os.Getenv("GIT_SSH_COMMAND") != "" && a.SSHKey == "" {
+			return nil
+		}
+		if cmd := a.SSHCommand(); cmd != "" && url != "" {
+			return []string{"GIT_SSH_COMMAND=" + cmd}
+		}
+		return nil
+	}
+	if a.Token == "" {
+		return nil
+	}
+	scheme, _, _ := strings.Cut(url, "://")
+	credentials := a.credentials(tokenUser(url))
+
+	// Append to any GIT_CONFIG_* entries already present in the environment.
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	return []string{
+		"GIT_CONFIG_COUNT=" + strconv.Itoa(n+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s://%s/.extraHeader", n, scheme, urlHost(url)),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", n, credentials),
+	}
+}
+
+// SSHCommand returns the ssh invocation selecting the key and known hosts file,
+// or an empty string when the SSH defaults apply.
+func (a Auth) SSHCommand() string {
+	args := []string{"ssh"}
+	if a.SSHKey != "" {
+		args = append(args, "-i", shellQuote(a.SSHKey), "-o", "IdentitiesOnly=yes")
+	}
+	if a.KnownHosts != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+shellQuote(a.KnownHosts))
+	}
+	if a.InsecureHostKeys {
+		args = append(args, "-o", "StrictHostKeyChecking=no")
+	} else if len(args) > 1 {
+		args = append(args, "-o", "StrictHostKeyChecking=yes")
+	}
+	if len(args) == 1 {
+		return ""
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s for the shell that git runs GIT_SSH_COMMAND with.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// credentials returns the base64-encoded basic auth credentials of the token.
+func (a Auth) credentials(user string) string {
+	return base64.StdEncoding.EncodeToString([]byte(user + ":" + a.Token))
+}
+
+// tokenUser returns the user name the host expects alongside a token.
+func tokenUser(url string) string {
+	if strings.Contains(urlHost(url), "gitlab") {
+		return "oauth2"
+	}
+	return "x-access-token"
+}
+
+// redact removes the credentials, plain or encoded, from text produced by git.
+func (a Auth) redact(s string) string {
+	if a.Token != "" {
+		for _, secret := range []string{a.Token, a.credentials("oauth2"), a.credentials("x-access-token")} {
+			s = strings.ReplaceAll(s, secret, "xxxxx")
+		}
+	}
+	return gitpath.Redact(s)
+}
+
+// run executes git in dir with the credentials for the remote URL and
+// includes its redacted error output in the returned error.
+func (a Auth) run(ctx context.Context, dir, url string, args ...string) error {
+	var out bytes.Buffer
+	err := a.exec(ctx, dir, url, &out, args...)
+	return a.result(ctx, args[0], err, out.String())
+}
+
+// runProgress runs a clone or fetch with --progress and reports
+// the transfer under msg while it is running.
+func (a Auth) runProgress(ctx context.Context, dir, url, msg string, args ...string) error {
+	args = append([]string{args[0], "--progress"}, args[1:]...)
+	var out bytes.Buffer
+	w := newProgressWriter(msg, &out)
+	err := a.exec(ctx, dir, url, w, args...)
+	_ = w.Close()
+	return a.result(ctx, args[0], err, out.String())
+}
+
+// exec executes git with the credentials for url and writes its output to w.
+func (a Auth) exec(ctx context.Context, dir, url string, w io.Writer, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), a.Env(url)...)
+	if a.skipLFS {
+		cmd.Env = append(cmd.Env, "GIT_LFS_SKIP_SMUDGE=1")
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+	return cmd.Run()
+}
+
+// result builds the error of a git command from its redacted output.
+func (a Auth) result(ctx context.Context, name string, err error, out string) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("git %s: %w", name, ctx.Err())
+	} else if err == nil {
+		return nil
+	}
+	if msg := strings.TrimSpace(a.redact(out)); msg != "" {
+		return fmt.Errorf("git %s: %w: %s", name, err, msg)
+	}
+	return fmt.Errorf("git %s: %w", name, err)
+}
+
+func isHTTP(url string) bool {
+	return strings.HasPrefix(strings.ToLower(url), "https://") || isPlainHTTP(url)
+}
+
+func isPlainHTTP(url string) bool {
+	return strings.HasPrefix(strings.ToLower(url), "http://")
+}
+
+func urlHost(url string) string {
+	host, _, _ := strings.Cut(gitpath.Normalize(url), "/")
+	return host
+}
@@ -0,0 +1,147 @@
+package gitclone
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitpath"
+	"github.com/i-zaitsev/gitcat/pkg/internal/utils"
+	"github.com/i-zaitsev/gitcat/pkg/log"
+)
+
+// Policy decides what happens when the clone target directory already exists.
+type Policy int
+
+const (
+	// Fail reports an ExistsError naming the conflicting directory.
+	Fail Policy = iota
+	// Reuse verifies that the directory is a clone of the same repository,
+	// fetches it and resets it to the requested ref.
+	Reuse
+	// Force deletes the directory, after confirmation, and clones again.
+	Force
+)
+
+// ExistsError is returned when the clone target exists and the policy does not allow touching it.
+type ExistsError struct {
+	Dir string
+}
+
+func (e *ExistsError) Error() string {
+	return fmt.Sprintf("clone target %s already exists and is not empty", e.Dir)
+}
+
+// ErrNotConfirmed is returned when replacing an existing clone target was declined.
+var ErrNotConfirmed = errors.New("replacing the existing clone target was not confirmed")
+
+// ErrUncommitted is returned when reusing a clone would discard changes made in it.
+var ErrUncommitted = errors.New("the existing clone has uncommitted changes")
+
+func cloneExisting(ctx context.Context, repoUrl *gitpath.GitPath, localDir string, opts Options) error {
+	switch opts.Existing {
+	case Reuse:
+		return reuse(ctx, repoUrl, localDir, opts)
+	case Force:
+		if opts.Confirm != nil && !opts.Confirm(ctx, localDir) {
+			return ErrNotConfirmed
+		}
+		log.Warn("removing existing clone target", "dir", localDir)
+		if err := os.RemoveAll(localDir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", localDir, err)
+		}
+		opts.Existing = Fail
+		return Clone(ctx, repoUrl, localDir, opts)
+	default:
+		return &ExistsError{Dir: localDir}
+	}
+}
+
+// reuse updates an existing clone of the same repository to the requested ref.
+func reuse(ctx context.Context, repoUrl *gitpath.GitPath, localDir string, opts Options) error {
+	remote, err := RemoteURL(ctx, localDir)
+	if err != nil {
+		return fmt.Errorf("cannot reuse %s: not a git clone: %w", localDir, err)
+	}
+	if gitpath.Normalize(remote) != gitpath.Normalize(repoUrl.CloneURL()) {
+		return fmt.Errorf("cannot reuse %s: it is a clone of %s, not %s",
+			localDir, gitpath.Redact(remote), repoUrl.CloneURL())
+	}
+
+	// The clone is reset to the fetched commit, which would discard changes.
+	if status, err := Output(ctx, localDir, "status", "--porcelain"); err != nil {
+		return fmt.Errorf("cannot reuse %s: %w", localDir, err)
+	} else if strings.TrimSpace(status) != "" {
+		return fmt.Errorf("cannot reuse %s: %w", localDir, ErrUncommitted)
+	}
+
+	ref := opts.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	log.Info("reusing existing clone", "dir", localDir, "ref", ref)
+
+	source, auth := "origin", opts.Auth
+	args := []string{"fetch"}
+	if opts.Mirror != "" {
+		source, auth = opts.Mirror, Auth{}
+	} else if opts.Depth > 0 && isShallow(ctx, localDir) {
+		// Fetching a full clone with --depth would make it shallow.
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if err := auth.runProgress(ctx, localDir, remote, "fetching", append(args, source, ref)...); err != nil {
+		return err
+	}
+	if opts.NoCheckout {
+		return run(ctx, localDir, "reset", "--soft", "FETCH_HEAD")
+	}
+	if err := opts.Auth.run(ctx, localDir, remote, "reset", "--hard", "FETCH_HEAD"); err != nil {
+		return err
+	}
+
+	if len(opts.Sparse) > 0 {
+		err = sparseCheckout(ctx, repoUrl, localDir, "HEAD", opts)
+	} else {
+		err = opts.Auth.run(ctx, localDir, remote, "sparse-checkout", "disable")
+	}
+	if err != nil {
+		return err
+	}
+	return updateSubmodules(ctx, repoUrl, localDir, opts)
+}
+
+// RemoteURL returns the URL of the origin remote of the repository.
+func RemoteURL(ctx context.Context, repoDir string) (string, error) {
+	out, err := Output(ctx, repoDir, "remote", "get-url", "origin")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// isShallow reports whether the repository in dir is a shallow clone.
+func isShallow(ctx context.Context, dir string) bool {
+	out, err := Output(ctx, dir, "rev-parse", "--is-shallow-repository")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// IsRepository reports whether dir is inside the working tree of a git repository.
+func IsRepository(ctx context.Context, dir string) bool {
+	out, err := Output(ctx, dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// isEmptyDir reports whether dir does not exist or has no entries.
+func isEmptyDir(dir string) bool {
+	f, err := os.Open(dir)
+	if err != nil {
+		return errors.Is(err, os.ErrNotExist)
+	}
+	defer utils.SilentClose(f)
+	_, err = f.Readdirnames(1)
+	return errors.Is(err, io.EOF)
+}
@@ -0,0 +1,163 @@
+package gitclone
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitpath"
+	"github.com/i-zaitsev/gitcat/pkg/internal/gittest"
+)
+
+// pushCommit adds a commit with the files to the main branch of the remote.
+func pushCommit(t *testing.T, remote *gitpath.GitPath, files map[string]string) string {
+	t.Helper()
+	work := filepath.Join(t.TempDir(), "work")
+	gittest.Git(t, "", "clone", "-q", remote.Path, work)
+	gittest.WriteFiles(t, work, files)
+	gittest.Git(t, work, "add", "-A")
+	gittest.Git(t, work, "commit", "-q", "-m", "update")
+	gittest.Git(t, work, "push", "-q", "origin", "main")
+	return gittest.Git(t, work, "rev-parse", "HEAD")
+}
+
+func TestReuse(t *testing.T) {
+	tests := []struct {
+		name        string
+		clone       Options
+		wantShallow string
+	}{
+		{"full clone stays full", Options{}, "false"},
+		{"shallow clone stays shallow", Options{Depth: 1}, "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newRemote(t, map[string]string{"README.md": "v1"}, map[string]string{"README.md": "v2"})
+			dir := filepath.Join(t.TempDir(), "clone")
+			if err := Clone(context.Background(), remote, dir, tt.clone); err != nil {
+				t.Fatal(err)
+			}
+			head := pushCommit(t, remote, map[string]string{"README.md": "v3"})
+
+			if err := Clone(context.Background(), remote, dir, Options{Depth: 1, Existing: Reuse}); err != nil {
+				t.Fatal(err)
+			}
+			if got := gittest.Git(t, dir, "rev-parse", "HEAD"); got != head {
+				t.Errorf("HEAD after reuse = %s, want %s", got, head)
+			}
+			if got := gittest.Git(t, dir, "rev-parse", "--is-shallow-repository"); got != tt.wantShallow {
+				t.Errorf("shallow after reuse = %s, want %s", got, tt.wantShallow)
+			}
+			if data, _ := os.ReadFile(filepath.Join(dir, "README.md")); string(data) != "v3" {
+				t.Errorf("README.md after reuse = %q, want %q", data, "v3")
+			}
+		})
+	}
+}
+
+func TestReuseUncommitted(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"modified", map[string]string{"README.md": "local edit"}},
+		{"untracked", map[string]string{"notes.txt": "local notes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newRemote(t, map[string]string{"README.md": "v1"})
+			dir := filepath.Join(t.TempDir(), "clone")
+			if err := Clone(context.Background(), remote, dir, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			gittest.WriteFiles(t, dir, tt.files)
+
+			err := Clone(context.Background(), remote, dir, Options{Existing: Reuse})
+			if !errors.Is(err, ErrUncommitted) {
+				t.Fatalf("Clone() error = %v, want %v", err, ErrUncommitted)
+			}
+			for name, want := range tt.files {
+				if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != want {
+					t.Errorf("%s after refused reuse = %q, want %q", name, data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReuseOtherRemote(t *testing.T) {
+	remote := newRemote(t, map[string]string{"README.md": "v1"})
+	other := newRemote(t, map[string]string{"README.md": "other"})
+	dir := filepath.Join(t.TempDir(), "clone")
+	if err := Clone(context.Background(), other, dir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	head := gittest.Git(t, dir, "rev-parse", "HEAD")
+
+	err := Clone(context.Background(), remote, dir, Options{Existing: Reuse})
+	if err == nil || !strings.Contains(err.Error(), "is a clone of") {
+		t.Fatalf("Clone() error = %v, want a remote mismatch", err)
+	}
+	if got := gittest.Git(t, dir, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD after refused reuse = %s, want %s", got, head)
+	}
+
+	if err := Clone(context.Background(), remote, t.TempDir(), Options{Existing: Reuse}); err != nil {
+		t.Errorf("Clone() into an empty directory with Reuse: %v", err)
+	}
+	plain := t.TempDir()
+	gittest.WriteFiles(t, plain, map[string]string{"x": "x"})
+	if err := Clone(context.Background(), remote, plain, Options{Existing: Reuse}); err == nil {
+		t.Error("Clone() reusing a directory that is not a clone succeeded")
+	}
+}
+
+func TestCloneExisting(t *testing.T) {
+	remote := newRemote(t, map[string]string{"README.md": "v1"})
+
+	tests := []struct {
+		name      string
+		opts      Options
+		wantErr   error
+		wantExist bool // the extra file in the directory is kept
+	}{
+		{"fail", Options{}, &ExistsError{}, true},
+		{"force declined", Options{Existing: Force, Confirm: func(context.Context, string) bool { return false }}, ErrNotConfirmed, true},
+		{"force confirmed", Options{Existing: Force, Confirm: func(context.Context, string) bool { return true }}, nil, false},
+		{"force without confirmation", Options{Existing: Force}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			gittest.WriteFiles(t, dir, map[string]string{"keep.txt": "mine"})
+
+			err := Clone(context.Background(), remote, dir, tt.opts)
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case *ExistsError:
+				if !errors.As(err, &want) || want.Dir != dir {
+					t.Fatalf("Clone() error = %v, want an ExistsError for %s", err, dir)
+				}
+			default:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Clone() error = %v, want %v", err, tt.wantErr)
+				}
+			}
+			_, statErr := os.Stat(filepath.Join(dir, "keep.txt"))
+			if kept := statErr == nil; kept != tt.wantExist {
+				t.Errorf("keep.txt kept = %v, want %v", kept, tt.wantExist)
+			}
+			if tt.wantErr == nil {
+				if data, _ := os.ReadFile(filepath.Join(dir, "README.md")); string(data) != "v1" {
+					t.Errorf("README.md after replacing = %q, want %q", data, "v1")
+				}
+			}
+		})
+	}
+}
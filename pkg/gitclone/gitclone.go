@@ -1,35 +1,231 @@
 package gitclone
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/i-zaitsev/gitcat/pkg/gitpath"
 	"github.com/i-zaitsev/gitcat/pkg/log"
 )
 
+// Options control how much of a remote repository is downloaded.
+// The zero value performs a plain full clone.
+type Options struct {
+	Depth      int        // number of commits to fetch, 0 for the full history
+	Filter     string     // partial clone filter spec, e.g. "blob:none"
+	Sparse     []string   // paths to check out in a cone-mode sparse checkout
+	Ref        string     // branch, tag or full commit hash to check out, empty for the default branch
+	Mirror     string     // local mirror to clone from; origin still points to the repository URL
+	NoCheckout bool       // leave the working tree empty, e.g. to read files from the object database
+	Submodules Submodules // submodules to check out after cloning, none by default
+	LFS        bool       // download Git LFS objects on checkout instead of keeping their pointer files
+	Existing   Policy     // what to do when the clone target already exists
+	Confirm    func(ctx context.Context, dir string) bool
+	Auth       Auth
+}
+
 // Clone clones a git repository via SSH or HTTPS.
 // For SSH, it is assumed that the SSH key is properly configured.
-// For HTTPS, only public repositories are supported (no authentication).
-func Clone(repoUrl *gitpath.GitPath, localDir string) error {
-	log.Debug("cloning repository", "url", repoUrl.Path, "dir", localDir)
-	cmd := exec.Command("git", "clone", repoUrl.Path, localDir)
-	if err := cmd.Run(); err != nil {
-		log.Error("git clone failed", "error", err, "url", repoUrl.Path)
+// For HTTPS, private repositories need a token in opts.Auth.
+// If localDir already exists and is not empty, opts.Existing decides whether
+// it is reused, replaced, or reported as an error.
+func Clone(ctx context.Context, repoUrl *gitpath.GitPath, localDir string, opts Options) error {
+	opts.Auth.skipLFS = !opts.LFS
+	if !isEmptyDir(localDir) {
+		return cloneExisting(ctx, repoUrl, localDir, opts)
+	}
+
+	log.Debug("cloning repository", "url", repoUrl.Redacted(), "dir", localDir, "ref", opts.Ref,
+		"depth", opts.Depth, "filter", opts.Filter, "sparse", opts.Sparse, "mirror", opts.Mirror)
+	source := repoUrl.Path
+	if opts.Mirror != "" {
+		source = opts.Mirror
+		opts.Depth, opts.Filter = 0, ""
+	}
+	if err := opts.Auth.runProgress(ctx, "", source, "cloning repository", cloneArgs(source, localDir, opts)...); err != nil {
+		log.Error("git clone failed", "error", err, "url", repoUrl.Redacted())
+		return err
+	}
+	if opts.Mirror != "" {
+		if err := SetRemote(ctx, localDir, repoUrl.Path); err != nil {
+			return err
+		}
+	}
+	if isCommit(opts.Ref) {
+		if err := checkoutCommit(ctx, repoUrl, localDir, opts); err != nil {
+			return err
+		}
+	} else if len(opts.Sparse) > 0 && !opts.NoCheckout {
+		if err := sparseCheckout(ctx, repoUrl, localDir, "HEAD", opts); err != nil {
+			return err
+		}
+	}
+	if err := updateSubmodules(ctx, repoUrl, localDir, opts); err != nil {
 		return err
 	}
 	log.Debug("repository cloned successfully", "dir", localDir)
 	return nil
 }
 
-// Status checks the git status of a repository.
-func Status(repoDir string) (string, error) {
-	log.Debug("checking git status", "dir", repoDir)
-	cmd := exec.Command("git", "status")
-	cmd.Dir = repoDir
-	output, err := cmd.CombinedOutput()
+func cloneArgs(url, localDir string, opts Options) []string {
+	args := []string{"clone"}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.Filter != "" {
+		args = append(args, "--filter="+opts.Filter)
+	}
+	if opts.NoCheckout || isCommit(opts.Ref) {
+		args = append(args, "--no-checkout")
+	} else if len(opts.Sparse) > 0 {
+		args = append(args, "--sparse")
+	}
+	if opts.Ref != "" && !isCommit(opts.Ref) {
+		args = append(args, "--branch", opts.Ref)
+	}
+	return append(args, "--", url, localDir)
+}
+
+// commitID matches full SHA-1 and SHA-256 commit hashes. Abbreviated hashes
+// cannot be fetched, so they are passed to git as branch or tag names.
+var commitID = regexp.MustCompile(`^(?:[0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// isCommit reports whether the ref is a commit hash rather than a branch or tag.
+func isCommit(ref string) bool {
+	return commitID.MatchString(ref)
+}
+
+// checkoutCommit fetches the commit of opts.Ref into a clone made without
+// a checkout, since git clone --branch only accepts branches and tags, and
+// checks it out.
+func checkoutCommit(ctx context.Context, repoUrl *gitpath.GitPath, localDir string, opts Options) error {
+	source, auth := "origin", opts.Auth
+	args := []string{"fetch"}
+	if opts.Mirror != "" {
+		source, auth = opts.Mirror, Auth{}
+	} else if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if err := auth.runProgress(ctx, localDir, repoUrl.Path, "fetching commit", append(args, source, opts.Ref)...); err != nil {
+		return err
+	}
+	if opts.NoCheckout {
+		return run(ctx, localDir, "update-ref", "--no-deref", "HEAD", "FETCH_HEAD")
+	}
+	if len(opts.Sparse) > 0 {
+		if err := sparseCheckout(ctx, repoUrl, localDir, "FETCH_HEAD", opts); err != nil {
+			return err
+		}
+	}
+	return opts.Auth.run(ctx, localDir, repoUrl.Path, "checkout", "--quiet", "FETCH_HEAD")
+}
+
+// sparseCheckout restricts the working tree to the cones containing the paths
+// of opts.Sparse in the tree of rev. Cone mode only accepts directories, so
+// file paths are replaced by their parent; files at the repository root are
+// always part of the checkout. Blobs missing from a partial clone are fetched
+// with the credentials for the repository URL.
+func sparseCheckout(ctx context.Context, repoUrl *gitpath.GitPath, repoDir, rev string, opts Options) error {
+	cones := make([]string, 0, len(opts.Sparse))
+	for _, p := range opts.Sparse {
+		if objectType(ctx, repoDir, rev+":"+p) == "blob" {
+			p = path.Dir(p)
+		}
+		if p != "." {
+			cones = append(cones, p)
+		}
+	}
+	if len(cones) == 0 {
+		return nil
+	}
+	log.Debug("setting sparse checkout", "dir", repoDir, "cones", cones)
+	args := append([]string{"sparse-checkout", "set", "--cone", "--"}, cones...)
+	if err := opts.Auth.run(ctx, repoDir, repoUrl.Path, args...); err != nil {
+		return fmt.Errorf("sparse checkout failed: %w", err)
+	}
+	return nil
+}
+
+// objectType returns the type of the named git object, or an empty string if it does not exist.
+func objectType(ctx context.Context, repoDir, object string) string {
+	out, err := Output(ctx, repoDir, "cat-file", "-t", object)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// Mirror creates a bare clone of the repository in dir that tracks all of its branches and tags.
+func Mirror(ctx context.Context, repoUrl *gitpath.GitPath, dir string, auth Auth) error {
+	log.Debug("creating mirror", "url", repoUrl.Redacted(), "dir", dir)
+	if err := auth.runProgress(ctx, "", repoUrl.Path, "creating mirror", "clone", "--bare", "--", repoUrl.Path, dir); err != nil {
+		return err
+	}
+	return run(ctx, dir, "config", "remote.origin.fetch", "+refs/heads/*:refs/heads/*")
+}
+
+// Fetch updates a bare clone created by Mirror, pruning deleted branches.
+func Fetch(ctx context.Context, dir string, auth Auth) error {
+	log.Debug("fetching mirror", "dir", dir)
+	remote, err := RemoteURL(ctx, dir)
+	if err != nil {
+		return fmt.Errorf("failed to get remote of %s: %w", dir, err)
+	}
+	return auth.runProgress(ctx, dir, remote, "fetching mirror", "fetch", "--prune", "--tags", "origin")
+}
+
+// SetRemote points the origin remote of the repository to the given URL.
+func SetRemote(ctx context.Context, repoDir, url string) error {
+	return run(ctx, repoDir, "remote", "set-url", "origin", url)
+}
+
+// RemoteRefs lists the names of the branches and tags of the remote
+// repository, without the refs/heads/ and refs/tags/ prefixes.
+func RemoteRefs(ctx context.Context, repoUrl *gitpath.GitPath, auth Auth) ([]string, error) {
+	var out bytes.Buffer
+	err := auth.exec(ctx, "", repoUrl.Path, &out, "ls-remote", "--heads", "--tags", repoUrl.Path)
+	if err := auth.result(ctx, "ls-remote", err, out.String()); err != nil {
+		return nil, err
+	}
+	var refs []string
+	for line := range strings.Lines(out.String()) {
+		_, name, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok || strings.HasSuffix(name, "^{}") {
+			continue
+		}
+		if branch, ok := strings.CutPrefix(name, "refs/heads/"); ok {
+			refs = append(refs, branch)
+		} else if tag, ok := strings.CutPrefix(name, "refs/tags/"); ok {
+			refs = append(refs, tag)
+		}
+	}
+	return refs, nil
+}
+
+// run executes git in dir without credentials.
+func run(ctx context.Context, dir string, args ...string) error {
+	return Auth{}.run(ctx, dir, "", args...)
+}
+
+// Output executes git in dir without credentials and returns its standard
+// output. The error includes the redacted error output of git.
+func Output(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
 	if err != nil {
-		log.Error("git status failed", "error", err, "dir", repoDir)
+		if msg := strings.TrimSpace(gitpath.Redact(stderr.String())); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
 		return "", err
 	}
-	return string(output), nil
+	return string(out), nil
 }
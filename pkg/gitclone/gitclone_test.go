@@ -0,0 +1,284 @@
+package gitclone
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitpath"
+	"github.com/i-zaitsev/gitcat/pkg/internal/gittest"
+)
+
+// newRemote creates a bare repository that serves partial clones,
+// and returns its file:// URL.
+func newRemote(t *testing.T, commits ...map[string]string) *gitpath.GitPath {
+	t.Helper()
+	src := gittest.NewRepo(t, commits...)
+	bare := filepath.Join(t.TempDir(), "remote.git")
+	gittest.Git(t, "", "clone", "-q", "--bare", src, bare)
+	gittest.Git(t, bare, "config", "uploadpack.allowFilter", "true")
+	repoUrl, err := gitpath.FromURL("file://" + filepath.ToSlash(bare))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repoUrl
+}
+
+func TestCloneArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"full", Options{}, []string{"clone", "--", "url", "dir"}},
+		{"shallow", Options{Depth: 1}, []string{"clone", "--depth", "1", "--", "url", "dir"}},
+		{"blobless", Options{Filter: "blob:none"}, []string{"clone", "--filter=blob:none", "--", "url", "dir"}},
+		{"sparse", Options{Sparse: []string{"pkg"}}, []string{"clone", "--sparse", "--", "url", "dir"}},
+		{
+			"no checkout wins over sparse",
+			Options{Sparse: []string{"pkg"}, NoCheckout: true},
+			[]string{"clone", "--no-checkout", "--", "url", "dir"},
+		},
+		{
+			"commit",
+			Options{Depth: 1, Ref: "0123456789abcdef0123456789abcdef01234567"},
+			[]string{"clone", "--depth", "1", "--no-checkout", "--", "url", "dir"},
+		},
+		{
+			"abbreviated commit is a branch",
+			Options{Ref: "0123456"},
+			[]string{"clone", "--branch", "0123456", "--", "url", "dir"},
+		},
+		{
+			"all",
+			Options{Depth: 2, Filter: "blob:none", Sparse: []string{"pkg"}, Ref: "v1"},
+			[]string{"clone", "--depth", "2", "--filter=blob:none", "--sparse", "--branch", "v1", "--", "url", "dir"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cloneArgs("url", "dir", tt.opts); !slices.Equal(got, tt.want) {
+				t.Errorf("cloneArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	remote := newRemote(t,
+		map[string]string{"README.md": "v1", "pkg/a/a.go": "a", "cmd/main.go": "main"},
+		map[string]string{"README.md": "v2", "pkg/b/b.go": "b"},
+	)
+
+	tests := []struct {
+		name        string
+		opts        Options
+		wantCommits string
+		wantFilter  string
+		wantFiles   []string
+	}{
+		{
+			name:        "full",
+			wantCommits: "2",
+			wantFiles:   []string{"README.md", "cmd/main.go", "pkg/a/a.go", "pkg/b/b.go"},
+		},
+		{
+			name:        "shallow",
+			opts:        Options{Depth: 1},
+			wantCommits: "1",
+			wantFiles:   []string{"README.md", "cmd/main.go", "pkg/a/a.go", "pkg/b/b.go"},
+		},
+		{
+			name:        "blobless",
+			opts:        Options{Depth: 1, Filter: "blob:none"},
+			wantCommits: "1",
+			wantFilter:  "blob:none",
+			wantFiles:   []string{"README.md", "cmd/main.go", "pkg/a/a.go", "pkg/b/b.go"},
+		},
+		{
+			name:        "sparse",
+			opts:        Options{Depth: 1, Filter: "blob:none", Sparse: []string{"pkg/a", "cmd/main.go"}},
+			wantCommits: "1",
+			wantFilter:  "blob:none",
+			wantFiles:   []string{"README.md", "cmd/main.go", "pkg/a/a.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "clone")
+			if err := Clone(context.Background(), remote, dir, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if got := gittest.Git(t, dir, "rev-list", "--count", "HEAD"); got != tt.wantCommits {
+				t.Errorf("commits = %s, want %s", got, tt.wantCommits)
+			}
+			filter, _ := Output(context.Background(), dir, "config", "remote.origin.partialclonefilter")
+			if got := strings.TrimSpace(filter); got != tt.wantFilter {
+				t.Errorf("partial clone filter = %q, want %q", got, tt.wantFilter)
+			}
+			if got := checkedOut(t, dir); !slices.Equal(got, tt.wantFiles) {
+				t.Errorf("checked out files = %q, want %q", got, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestCloneCommit(t *testing.T) {
+	remote := newRemote(t,
+		map[string]string{"README.md": "v1", "pkg/a/a.go": "a", "cmd/main.go": "main"},
+		map[string]string{"README.md": "v2", "pkg/b/b.go": "b"},
+	)
+	bare := strings.TrimPrefix(remote.Path, "file://")
+	first := gittest.Git(t, bare, "rev-parse", "HEAD~1")
+
+	tests := []struct {
+		name      string
+		opts      Options
+		wantFiles []string
+	}{
+		{"full", Options{Ref: first}, []string{"README.md", "cmd/main.go", "pkg/a/a.go"}},
+		{"shallow", Options{Ref: first, Depth: 1, Filter: "blob:none"}, []string{"README.md", "cmd/main.go", "pkg/a/a.go"}},
+		{"sparse", Options{Ref: first, Depth: 1, Sparse: []string{"pkg"}}, []string{"README.md", "pkg/a/a.go"}},
+		{"no checkout", Options{Ref: first, Depth: 1, NoCheckout: true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "clone")
+			if err := Clone(context.Background(), remote, dir, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if got := gittest.Git(t, dir, "rev-parse", "HEAD"); got != first {
+				t.Errorf("HEAD = %s, want %s", got, first)
+			}
+			if got := checkedOut(t, dir); !slices.Equal(got, tt.wantFiles) {
+				t.Errorf("checked out files = %q, want %q", got, tt.wantFiles)
+			}
+			if got := gittest.Git(t, dir, "show", "HEAD:README.md"); got != "v1" {
+				t.Errorf("README.md at HEAD = %q, want %q", got, "v1")
+			}
+		})
+	}
+}
+
+func TestSparseCheckout(t *testing.T) {
+	remote := newRemote(t, map[string]string{
+		"go.mod": "module x", "pkg/a/a.go": "a", "pkg/b/b.go": "b", "cmd/x/main.go": "main",
+	})
+
+	tests := []struct {
+		name      string
+		paths     []string
+		wantFiles []string
+	}{
+		{"directory", []string{"pkg/a"}, []string{"go.mod", "pkg/a/a.go"}},
+		{"file uses its directory", []string{"cmd/x/main.go"}, []string{"cmd/x/main.go", "go.mod"}},
+		{"several", []string{"pkg/b", "cmd"}, []string{"cmd/x/main.go", "go.mod", "pkg/b/b.go"}},
+		{"root file", []string{"go.mod"}, []string{"cmd/x/main.go", "go.mod", "pkg/a/a.go", "pkg/b/b.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "clone")
+			if err := Clone(context.Background(), remote, dir, Options{}); err != nil {
+				t.Fatal(err)
+			}
+			if err := sparseCheckout(context.Background(), remote, dir, "HEAD", Options{Sparse: tt.paths}); err != nil {
+				t.Fatal(err)
+			}
+			if got := checkedOut(t, dir); !slices.Equal(got, tt.wantFiles) {
+				t.Errorf("checked out files = %q, want %q", got, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestCloneLFS(t *testing.T) {
+	// A stand-in for git lfs that reports whether downloading was skipped.
+	config := filepath.Join(t.TempDir(), "gitconfig")
+	gittest.WriteFiles(t, filepath.Dir(config), map[string]string{
+		"gitconfig": "[filter \"lfs\"]\n\tsmudge = \"if [ -n \\\"$GIT_LFS_SKIP_SMUDGE\\\" ]; then cat; else echo object; fi\"\n",
+	})
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"pointer", Options{}, "pointer\n"},
+		{"sparse pointer", Options{Depth: 1, Filter: "blob:none", Sparse: []string{"model.bin"}}, "pointer\n"},
+		{"fetch", Options{LFS: true}, "object\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newRemote(t, map[string]string{".gitattributes": "*.bin filter=lfs\n", "model.bin": "pointer\n"})
+			t.Setenv("GIT_CONFIG_GLOBAL", config)
+			dir := filepath.Join(t.TempDir(), "clone")
+			if err := Clone(context.Background(), remote, dir, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filepath.Join(dir, "model.bin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("model.bin = %q, want %q", data, tt.want)
+			}
+
+			if tt.opts.LFS {
+				// The stand-in has no clean filter, so the object shows as a change.
+				return
+			}
+			// Reusing the clone checks out the updated pointer files too.
+			pushCommit(t, remote, map[string]string{"model.bin": "pointer\nupdated\n"})
+			tt.opts.Existing = Reuse
+			if err := Clone(context.Background(), remote, dir, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := os.ReadFile(filepath.Join(dir, "model.bin")); string(data) != "pointer\nupdated\n" {
+				t.Errorf("model.bin after reuse = %q, want %q", data, "pointer\nupdated\n")
+			}
+		})
+	}
+}
+
+// checkedOut returns the sorted paths of the files in the working tree of dir.
+func checkedOut(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			rel, _ := filepath.Rel(dir, p)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(files)
+	return files
+}
+
+func TestRemoteRefs(t *testing.T) {
+	remote := newRemote(t, map[string]string{"README.md": "v1\n"})
+	bare := strings.TrimPrefix(remote.Path, "file://")
+	gittest.Git(t, bare, "branch", "release/2.3", "main")
+	gittest.Git(t, bare, "tag", "-a", "-m", "v1", "v1.0", "main")
+
+	refs, err := RemoteRefs(context.Background(), remote, Auth{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(refs)
+	if want := []string{"main", "release/2.3", "v1.0"}; !slices.Equal(refs, want) {
+		t.Errorf("RemoteRefs() = %q, want %q", refs, want)
+	}
+}
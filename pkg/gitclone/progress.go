@@ -0,0 +1,73 @@
+package gitclone
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/i-zaitsev/gitcat/pkg/log"
+)
+
+// progressLine matches the counters git prints with --progress, e.g.
+// "Receiving objects:  45% (450/1000), 1.20 MiB | 2.40 MiB/s".
+var progressLine = regexp.MustCompile(`^(?:remote: )?([A-Z][A-Za-z ]+):\s+(\d+)% \((\d+/\d+)\)(?:, (.*))?$`)
+
+// progressWriter reports the progress lines written by git and passes
+// all other output through to out, so error messages stay readable.
+type progressWriter struct {
+	progress *log.Progress
+	out      io.Writer
+	partial  []byte
+}
+
+func newProgressWriter(msg string, out io.Writer) *progressWriter {
+	return &progressWriter{progress: log.NewProgress(msg), out: out}
+}
+
+// Write splits the output on carriage returns and newlines, since git
+// redraws its counters in place.
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexAny(w.partial, "\r\n")
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.partial[:i])
+		w.partial = w.partial[i+1:]
+		if err := w.line(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+func (w *progressWriter) line(line string) error {
+	status := strings.TrimSuffix(strings.TrimSpace(line), ", done.")
+	m := progressLine.FindStringSubmatch(status)
+	if m == nil {
+		if line == "" {
+			return nil
+		}
+		_, err := io.WriteString(w.out, line+"\n")
+		return err
+	}
+
+	args := []any{"phase", m[1], "percent", m[2], "objects", m[3]}
+	if received, throughput, ok := strings.Cut(m[4], " | "); ok {
+		args = append(args, "received", received, "throughput", throughput)
+	}
+	w.progress.Update(strings.TrimPrefix(status, "remote: "), args...)
+	return nil
+}
+
+// Close writes any unterminated output and clears the progress line.
+func (w *progressWriter) Close() error {
+	w.progress.Done()
+	if len(w.partial) == 0 {
+		return nil
+	}
+	line := string(w.partial)
+	w.partial = nil
+	return w.line(line)
+}
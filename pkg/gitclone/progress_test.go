@@ -0,0 +1,81 @@
+package gitclone
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string // phase, percent, objects and the rest, nil for no match
+	}{
+		{
+			"Receiving objects:  45% (450/1000), 1.20 MiB | 2.40 MiB/s",
+			[]string{"Receiving objects", "45", "450/1000", "1.20 MiB | 2.40 MiB/s"},
+		},
+		{"remote: Counting objects: 100% (12/12)", []string{"Counting objects", "100", "12/12", ""}},
+		{"Resolving deltas:   0% (0/7)", []string{"Resolving deltas", "0", "0/7", ""}},
+		{"Updating files: 100% (3/3)", []string{"Updating files", "100", "3/3", ""}},
+		{"remote: Enumerating objects: 12, done.", nil},
+		{"fatal: repository not found", nil},
+		{"Cloning into 'repo'...", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		m := progressLine.FindStringSubmatch(tt.line)
+		var got []string
+		if m != nil {
+			got = m[1:]
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("progressLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestProgressWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{
+			name:   "counters redrawn with carriage returns",
+			writes: []string{"Receiving objects:  10% (1/10)\rReceiving objects: 100% (10/10), done.\n"},
+		},
+		{
+			name:   "messages pass through",
+			writes: []string{"Cloning into 'repo'...\n", "fatal: repository not found\n"},
+			want:   "Cloning into 'repo'...\nfatal: repository not found\n",
+		},
+		{
+			name:   "lines split across writes",
+			writes: []string{"Resolving del", "tas:  50% (1/2)\rwarn", "ing: redirecting\r", "\nerror: x"},
+			want:   "warning: redirecting\nerror: x\n",
+		},
+		{
+			name:   "remote counters with messages",
+			writes: []string{"remote: Counting objects: 100% (3/3), done.\r\nremote: Total 3 (delta 0)\r\n"},
+			want:   "remote: Total 3 (delta 0)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			w := newProgressWriter("cloning", &out)
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); err != nil || n != len(s) {
+					t.Fatalf("Write(%q) = %d, %v", s, n, err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package gitclone
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/i-zaitsev/gitcat/pkg/log"
+)
+
+// Change describes how a file differs between HEAD, the index and the working tree.
+type Change struct {
+	Staged    bool // the index differs from HEAD
+	Modified  bool // the working tree differs from the index
+	Deleted   bool // the file was deleted from the working tree
+	Untracked bool // the file is not in the index
+}
+
+// String returns the comma-separated states of the change, e.g. "staged,modified".
+func (c Change) String() string {
+	var states []string
+	for _, s := range []struct {
+		set  bool
+		name string
+	}{
+		{c.Staged, "staged"},
+		{c.Modified, "modified"},
+		{c.Deleted, "deleted"},
+		{c.Untracked, "untracked"},
+	} {
+		if s.set {
+			states = append(states, s.name)
+		}
+	}
+	return strings.Join(states, ",")
+}
+
+// Status returns the changed files under repoDir by their path relative
+// to it. Unchanged files are not included.
+func Status(ctx context.Context, repoDir string) (map[string]Change, error) {
+	log.Debug("checking git status", "dir", repoDir)
+	prefix, err := Output(ctx, repoDir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %s", repoDir)
+	}
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain=v1", "-z", "--untracked-files=all", "--", ".")
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		log.Error("git status failed", "error", err, "dir", repoDir)
+		return nil, err
+	}
+	return parseStatus(string(out), strings.TrimSpace(prefix))
+}
+
+// parseStatus parses the output of git status --porcelain=v1 -z, in which
+// every record is "XY path" and renames are followed by the original path.
+// Paths are relative to the repository root and have prefix removed.
+func parseStatus(out, prefix string) (map[string]Change, error) {
+	changes := make(map[string]Change)
+	records := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if record == "" {
+			continue
+		}
+		if len(record) < 4 {
+			return nil, fmt.Errorf("unexpected git status record %q", record)
+		}
+		x, y, path := record[0], record[1], record[3:]
+		if x == 'R' || x == 'C' {
+			i++ // skip the original path
+		}
+
+		var c Change
+		switch {
+		case x == '?' && y == '?':
+			c.Untracked = true
+		case x == '!':
+			continue
+		default:
+			c.Staged = x != ' '
+			c.Modified = y == 'M' || y == 'T'
+			c.Deleted = y == 'D'
+		}
+		changes[strings.TrimPrefix(path, prefix)] = c
+	}
+	return changes, nil
+}
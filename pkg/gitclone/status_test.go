@@ -0,0 +1,135 @@
+package gitclone
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/i-zaitsev/gitcat/pkg/internal/gittest"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []string
+		prefix  string
+		want    map[string]Change
+	}{
+		{
+			name:    "modified and deleted",
+			records: []string{" M a.go", " D b.go", " T link"},
+			want: map[string]Change{
+				"a.go": {Modified: true},
+				"b.go": {Deleted: true},
+				"link": {Modified: true},
+			},
+		},
+		{
+			name:    "staged",
+			records: []string{"M  a.go", "A  new.go", "D  gone.go"},
+			want: map[string]Change{
+				"a.go":    {Staged: true},
+				"new.go":  {Staged: true},
+				"gone.go": {Staged: true},
+			},
+		},
+		{
+			name:    "staged and modified",
+			records: []string{"MM a.go", "AD b.go"},
+			want: map[string]Change{
+				"a.go": {Staged: true, Modified: true},
+				"b.go": {Staged: true, Deleted: true},
+			},
+		},
+		{
+			name:    "untracked",
+			records: []string{"?? dir/new file.txt"},
+			want:    map[string]Change{"dir/new file.txt": {Untracked: true}},
+		},
+		{
+			name:    "ignored",
+			records: []string{"!! build/out"},
+			want:    map[string]Change{},
+		},
+		{
+			name:    "renames skip the original path",
+			records: []string{"R  new.go", "old.go", "RM moved.go", "orig.go", "C  copy.go", "src.go", " M after.go"},
+			want: map[string]Change{
+				"new.go":   {Staged: true},
+				"moved.go": {Staged: true, Modified: true},
+				"copy.go":  {Staged: true},
+				"after.go": {Modified: true},
+			},
+		},
+		{
+			name:    "subdirectory prefix",
+			records: []string{" M pkg/ls/ls.go", "?? pkg/ls/new.go", "R  pkg/ls/b.go", "pkg/a.go"},
+			prefix:  "pkg/ls/",
+			want: map[string]Change{
+				"ls.go":  {Modified: true},
+				"new.go": {Untracked: true},
+				"b.go":   {Staged: true},
+			},
+		},
+		{
+			name: "empty",
+			want: map[string]Change{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := ""
+			if len(tt.records) > 0 {
+				out = strings.Join(tt.records, "\x00") + "\x00"
+			}
+			got, err := parseStatus(out, tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("parseStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatusInvalid(t *testing.T) {
+	if got, err := parseStatus("M\x00", ""); err == nil {
+		t.Errorf("parseStatus() = %v, want an error", got)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	dir := gittest.NewRepo(t, map[string]string{"a.go": "a", "pkg/b.go": "b", "pkg/c.go": "c", "pkg/d.go": "d"})
+	gittest.Git(t, dir, "mv", "pkg/c.go", "pkg/e.go")
+	gittest.WriteFiles(t, dir, map[string]string{"a.go": "changed", "pkg/b.go": "staged", "pkg/new.go": "new"})
+	gittest.Git(t, dir, "add", "pkg/b.go")
+	gittest.WriteFiles(t, dir, map[string]string{"pkg/b.go": "modified"})
+
+	tests := []struct {
+		dir  string
+		want map[string]Change
+	}{
+		{dir, map[string]Change{
+			"a.go":       {Modified: true},
+			"pkg/b.go":   {Staged: true, Modified: true},
+			"pkg/e.go":   {Staged: true},
+			"pkg/new.go": {Untracked: true},
+		}},
+		{dir + "/pkg", map[string]Change{
+			"b.go":   {Staged: true, Modified: true},
+			"e.go":   {Staged: true},
+			"new.go": {Untracked: true},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := Status(context.Background(), tt.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("Status(%s) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
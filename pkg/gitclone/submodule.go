@@ -0,0 +1,127 @@
+package gitclone
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitpath"
+	"github.com/i-zaitsev/gitcat/pkg/log"
+)
+
+// Submodules decides which submodules are checked out and listed with
+// the repository, and implements flag.Value interface.
+type Submodules string
+
+const (
+	SubmodulesNone      Submodules = "none"      // leave submodules out
+	SubmodulesShallow   Submodules = "shallow"   // only the submodules of the repository itself
+	SubmodulesRecursive Submodules = "recursive" // submodules of submodules too
+)
+
+// String returns the string representation of the mode, empty when it is
+// not set: clones then leave submodules out, while local repositories keep
+// the ones that are checked out.
+func (s *Submodules) String() string {
+	if s == nil {
+		return ""
+	}
+	return string(*s)
+}
+
+// Set validates and sets the mode.
+func (s *Submodules) Set(value string) error {
+	switch Submodules(value) {
+	case SubmodulesNone, SubmodulesShallow, SubmodulesRecursive:
+		*s = Submodules(value)
+		return nil
+	default:
+		return fmt.Errorf("invalid submodules mode %q: must be one of: none, shallow, recursive", value)
+	}
+}
+
+// Includes reports whether the files of a submodule at the given depth are listed.
+func (s Submodules) Includes(depth int) bool {
+	switch s {
+	case SubmodulesShallow:
+		return depth == 1
+	case SubmodulesRecursive:
+		return true
+	default:
+		return false
+	}
+}
+
+// Submodule is a submodule of a repository.
+type Submodule struct {
+	Path        string // slash-separated and relative to the listed directory
+	Commit      string // commit checked out in the submodule, or the pinned one if it is not initialized
+	Depth       int    // 1 for submodules of the repository, 2 for theirs, and so on
+	Initialized bool
+}
+
+// String returns the path and commit of the submodule, e.g. "lib@3f2c1a0...".
+func (s Submodule) String() string {
+	return s.Path + "@" + s.Commit
+}
+
+// ListSubmodules returns the submodules of the repository found under repoDir,
+// including nested ones.
+func ListSubmodules(ctx context.Context, repoDir string) ([]Submodule, error) {
+	out, err := Output(ctx, repoDir, "submodule", "status", "--recursive")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list submodules of %s: %w", repoDir, err)
+	}
+	return parseSubmodules(out), nil
+}
+
+// parseSubmodules parses the output of git submodule status, in which every
+// line is a state character, the commit, the path and an optional description
+// in parentheses. Submodules outside the current directory are left out.
+func parseSubmodules(out string) []Submodule {
+	var modules []Submodule
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		commit, rest, _ := strings.Cut(line[1:], " ")
+		if i := strings.LastIndex(rest, " ("); i >= 0 && strings.HasSuffix(rest, ")") {
+			rest = rest[:i]
+		}
+		if rest == "" || strings.HasPrefix(rest, "../") {
+			continue
+		}
+		modules = append(modules, Submodule{
+			Path:        rest,
+			Commit:      commit,
+			Initialized: line[0] != '-',
+		})
+	}
+
+	for i := range modules {
+		modules[i].Depth = 1
+		for _, parent := range modules {
+			if strings.HasPrefix(modules[i].Path, parent.Path+"/") {
+				modules[i].Depth++
+			}
+		}
+	}
+	return modules
+}
+
+// updateSubmodules checks out the submodules of a clone at their pinned commits.
+// The credentials for the repository URL also apply to submodules on the same host.
+func updateSubmodules(ctx context.Context, repoUrl *gitpath.GitPath, repoDir string, opts Options) error {
+	if !opts.Submodules.Includes(1) || opts.NoCheckout {
+		return nil
+	}
+	args := []string{"submodule", "update", "--init"}
+	if opts.Submodules == SubmodulesRecursive {
+		args = append(args, "--recursive")
+	}
+	log.Info("checking out submodules", "dir", repoDir, "mode", opts.Submodules)
+	if err := opts.Auth.run(ctx, repoDir, repoUrl.Path, args...); err != nil {
+		return fmt.Errorf("failed to check out submodules: %w", err)
+	}
+	return nil
+}
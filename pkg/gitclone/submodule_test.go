@@ -0,0 +1,101 @@
+package gitclone
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSubmodules(t *testing.T) {
+	const commit = "0123456789abcdef0123456789abcdef01234567"
+
+	tests := []struct {
+		name string
+		out  string
+		want []Submodule
+	}{
+		{
+			name: "initialized and not",
+			out: " " + commit + " lib (v1.0.0)\n" +
+				"-" + commit + " vendor/tool\n" +
+				"+" + commit + " ext (heads/main)\n",
+			want: []Submodule{
+				{Path: "lib", Commit: commit, Depth: 1, Initialized: true},
+				{Path: "vendor/tool", Commit: commit, Depth: 1},
+				{Path: "ext", Commit: commit, Depth: 1, Initialized: true},
+			},
+		},
+		{
+			name: "nested",
+			out: " " + commit + " lib (v1)\n" +
+				" " + commit + " lib/deps/a (v2)\n" +
+				" " + commit + " lib/deps/a/inner\n" +
+				" " + commit + " library\n",
+			want: []Submodule{
+				{Path: "lib", Commit: commit, Depth: 1, Initialized: true},
+				{Path: "lib/deps/a", Commit: commit, Depth: 2, Initialized: true},
+				{Path: "lib/deps/a/inner", Commit: commit, Depth: 3, Initialized: true},
+				{Path: "library", Commit: commit, Depth: 1, Initialized: true},
+			},
+		},
+		{
+			name: "outside the directory",
+			out:  " " + commit + " ../lib (v1)\n" + " " + commit + " sub\n",
+			want: []Submodule{{Path: "sub", Commit: commit, Depth: 1, Initialized: true}},
+		},
+		{
+			name: "path with spaces and parentheses",
+			out:  " " + commit + " my lib (old) (v1)\n",
+			want: []Submodule{{Path: "my lib (old)", Commit: commit, Depth: 1, Initialized: true}},
+		},
+		{
+			name: "conflict",
+			out:  "U" + commit + " lib\n",
+			want: []Submodule{{Path: "lib", Commit: commit, Depth: 1, Initialized: true}},
+		},
+		{name: "none", out: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSubmodules(tt.out); !slices.Equal(got, tt.want) {
+				t.Errorf("parseSubmodules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmodulesIncludes(t *testing.T) {
+	tests := []struct {
+		mode Submodules
+		want []bool // for depths 1 to 3
+	}{
+		{"", []bool{false, false, false}},
+		{SubmodulesNone, []bool{false, false, false}},
+		{SubmodulesShallow, []bool{true, false, false}},
+		{SubmodulesRecursive, []bool{true, true, true}},
+	}
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := tt.mode.Includes(i + 1); got != want {
+				t.Errorf("Submodules(%q).Includes(%d) = %v, want %v", tt.mode, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestSubmodulesSet(t *testing.T) {
+	for _, value := range []string{"none", "shallow", "recursive"} {
+		var s Submodules
+		if err := s.Set(value); err != nil {
+			t.Errorf("Set(%q) error: %v", value, err)
+		}
+		if s.String() != value {
+			t.Errorf("String() after Set(%q) = %q", value, s.String())
+		}
+	}
+	for _, value := range []string{"", "all", "Recursive"} {
+		var s Submodules
+		if err := s.Set(value); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", value)
+		}
+	}
+}
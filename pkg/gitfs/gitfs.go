@@ -0,0 +1,265 @@
+// Package gitfs reads the tree of a commit directly from the object
+// database of a git repository, without a working tree.
+package gitfs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitclone"
+	"github.com/i-zaitsev/gitcat/pkg/internal/memfs"
+	"github.com/i-zaitsev/gitcat/pkg/log"
+)
+
+// Entry is a file of a git tree.
+type Entry struct {
+	Path string
+	Mode fs.FileMode
+	OID  string
+	Size int64
+}
+
+// FS is a read-only file system over the tree of a commit. Blobs are read
+// on demand from a git cat-file --batch process that runs until Close.
+type FS struct {
+	*memfs.FS
+	cat *catFile
+}
+
+// New lists the tree of rev in repoDir, including loose and packed objects.
+// In a subdirectory of a repository, only the files under it are listed.
+// Submodules are not part of the file system.
+func New(ctx context.Context, repoDir, rev string) (*FS, error) {
+	entries, err := ListTree(ctx, repoDir, rev)
+	if err != nil {
+		return nil, err
+	}
+	modTime, err := commitTime(ctx, repoDir, rev)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("read git tree", "dir", repoDir, "rev", rev, "entries", len(entries))
+	return newFS(ctx, repoDir, entries, modTime)
+}
+
+// NewIndex lists the files staged in the index of the repository in repoDir.
+func NewIndex(ctx context.Context, repoDir string) (*FS, error) {
+	entries, err := ListIndex(ctx, repoDir)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("read git index", "dir", repoDir, "entries", len(entries))
+	return newFS(ctx, repoDir, entries, time.Time{})
+}
+
+func newFS(ctx context.Context, repoDir string, entries []Entry, modTime time.Time) (*FS, error) {
+	cat, err := startCatFile(ctx, repoDir)
+	if err != nil {
+		return nil, err
+	}
+	fsys := &FS{FS: memfs.New(), cat: cat}
+	for _, e := range entries {
+		if e.Mode&fs.ModeType == fs.ModeDir {
+			log.Debug("skipping submodule", "path", e.Path, "commit", e.OID)
+			continue
+		}
+		fsys.Add(e.Path, e.Size, e.Mode, modTime, func() ([]byte, error) {
+			return cat.read(e.OID)
+		})
+	}
+	return fsys, nil
+}
+
+// Close stops the cat-file process.
+func (f *FS) Close() error {
+	return f.cat.close()
+}
+
+// ListTree returns the entries of the tree of rev, recursively.
+// Submodules are reported as directories with the commit they pin as OID.
+func ListTree(ctx context.Context, repoDir, rev string) ([]Entry, error) {
+	out, err := gitclone.Output(ctx, repoDir, "ls-tree", "-r", "-l", "-z", rev, "--")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tree of %s: %w", rev, err)
+	}
+
+	var entries []Entry
+	for _, record := range strings.Split(strings.TrimSuffix(out, "\x00"), "\x00") {
+		if record == "" {
+			continue
+		}
+		meta, path, ok := strings.Cut(record, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected ls-tree output %q", record)
+		}
+		e := Entry{Path: path, Mode: fileMode(fields[0]), OID: fields[2]}
+		if fields[3] != "-" {
+			if e.Size, err = strconv.ParseInt(fields[3], 10, 64); err != nil {
+				return nil, fmt.Errorf("unexpected ls-tree size %q: %w", fields[3], err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ListIndex returns the entries staged in the index. Unmerged entries are skipped.
+func ListIndex(ctx context.Context, repoDir string) ([]Entry, error) {
+	out, err := gitclone.Output(ctx, repoDir, "ls-files", "--stage", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list index: %w", err)
+	}
+
+	var (
+		entries []Entry
+		oids    []string
+	)
+	for _, record := range strings.Split(strings.TrimSuffix(out, "\x00"), "\x00") {
+		if record == "" {
+			continue
+		}
+		meta, path, ok := strings.Cut(record, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected ls-files output %q", record)
+		}
+		if fields[2] != "0" {
+			log.Warn("skipping unmerged file", "path", path)
+			continue
+		}
+		e := Entry{Path: path, Mode: fileMode(fields[0]), OID: fields[1]}
+		if e.Mode&fs.ModeType != fs.ModeDir {
+			oids = append(oids, e.OID)
+		}
+		entries = append(entries, e)
+	}
+
+	sizes, err := objectSizes(ctx, repoDir, oids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i].Size = sizes[entries[i].OID]
+	}
+	return entries, nil
+}
+
+// objectSizes returns the sizes of the objects with git cat-file --batch-check.
+func objectSizes(ctx context.Context, repoDir string, oids []string) (map[string]int64, error) {
+	sizes := make(map[string]int64, len(oids))
+	if len(oids) == 0 {
+		return sizes, nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "--batch-check=%(objectname) %(objectsize)")
+	cmd.Dir = repoDir
+	cmd.Stdin = strings.NewReader(strings.Join(oids, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object sizes: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		oid, size, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("unexpected cat-file output %q", line)
+		}
+		if sizes[oid], err = strconv.ParseInt(size, 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected cat-file output %q: %w", line, err)
+		}
+	}
+	return sizes, nil
+}
+
+// fileMode converts a git tree entry mode to a file mode.
+func fileMode(mode string) fs.FileMode {
+	switch mode {
+	case "100755":
+		return 0755
+	case "120000":
+		return fs.ModeSymlink | 0777
+	case "160000":
+		return fs.ModeDir | 0755
+	default:
+		return 0644
+	}
+}
+
+// commitTime returns the committer date of rev, used as the modification time of its files.
+func commitTime(ctx context.Context, repoDir, rev string) (time.Time, error) {
+	out, err := gitclone.Output(ctx, repoDir, "show", "-s", "--format=%ct", rev, "--")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read commit %s: %w", rev, err)
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected commit time %q: %w", out, err)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// catFile reads objects through a single git cat-file --batch process.
+type catFile struct {
+	mu  sync.Mutex
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+func startCatFile(ctx context.Context, repoDir string) (*catFile, error) {
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "--batch")
+	cmd.Dir = repoDir
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start git cat-file: %w", err)
+	}
+	return &catFile{cmd: cmd, in: in, out: bufio.NewReader(out)}, nil
+}
+
+// read returns the content of the object.
+func (c *catFile) read(oid string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := io.WriteString(c.in, oid+"\n"); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	header, err := c.out.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("git cat-file: object %s %s", oid, strings.TrimSpace(header))
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: unexpected header %q", header)
+	}
+	data := make([]byte, size+1) // content is followed by a newline
+	if _, err := io.ReadFull(c.out, data); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	return data[:size], nil
+}
+
+func (c *catFile) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_ = c.in.Close()
+	return c.cmd.Wait()
+}
@@ -0,0 +1,187 @@
+package gitfs
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/i-zaitsev/gitcat/pkg/internal/gittest"
+)
+
+// newTestRepo creates a repository with a regular file, an executable,
+// a symlink, a nested file and a submodule entry.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	dir := gittest.NewRepo(t, map[string]string{
+		"README.md":      "# test\n",
+		"run.sh":         "#!/bin/sh\n",
+		"pkg/a/a.go":     "package a\n",
+		"pkg/b/b b.go":   "package b\n",
+		"docs/empty.txt": "",
+	})
+	if err := os.Chmod(filepath.Join(dir, "run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("README.md", filepath.Join(dir, "link.md")); err != nil {
+		t.Fatal(err)
+	}
+	head := gittest.Git(t, dir, "rev-parse", "HEAD")
+	gittest.Git(t, dir, "add", "-A")
+	gittest.Git(t, dir, "update-index", "--add", "--cacheinfo", "160000,"+head+",sub")
+	gittest.Git(t, dir, "commit", "-q", "-m", "modes")
+	return dir
+}
+
+func TestListTree(t *testing.T) {
+	dir := newTestRepo(t)
+	head := gittest.Git(t, dir, "rev-parse", "HEAD~1")
+
+	entries, err := ListTree(context.Background(), dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Entry, len(entries))
+	for _, e := range entries {
+		got[e.Path] = e
+	}
+
+	tests := []struct {
+		path string
+		mode fs.FileMode
+		size int64
+	}{
+		{"README.md", 0644, 7},
+		{"run.sh", 0755, 10},
+		{"link.md", fs.ModeSymlink | 0777, 9},
+		{"pkg/a/a.go", 0644, 10},
+		{"pkg/b/b b.go", 0644, 10},
+		{"docs/empty.txt", 0644, 0},
+		{"sub", fs.ModeDir | 0755, 0},
+	}
+	if len(entries) != len(tests) {
+		t.Errorf("ListTree() returned %d entries, want %d", len(entries), len(tests))
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			e, ok := got[tt.path]
+			if !ok {
+				t.Fatalf("ListTree() has no entry %q", tt.path)
+			}
+			if e.Mode != tt.mode || e.Size != tt.size {
+				t.Errorf("entry %q = mode %v size %d, want mode %v size %d", tt.path, e.Mode, e.Size, tt.mode, tt.size)
+			}
+		})
+	}
+	if sub := got["sub"]; sub.OID != head {
+		t.Errorf("submodule OID = %s, want %s", sub.OID, head)
+	}
+}
+
+func TestListTreeSubdir(t *testing.T) {
+	dir := newTestRepo(t)
+
+	entries, err := ListTree(context.Background(), filepath.Join(dir, "pkg"), "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Path)
+	}
+	want := []string{"a/a.go", "b/b b.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ListTree() in a subdirectory = %q, want %q", got, want)
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir := newTestRepo(t)
+	gittest.WriteFiles(t, dir, map[string]string{"README.md": "changed in the working tree\n"})
+
+	fsys, err := New(context.Background(), dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := fsys.Close(); err != nil {
+			t.Errorf("Close() error: %v", err)
+		}
+	}()
+
+	if err := fstest.TestFS(fsys, "README.md", "run.sh", "link.md", "pkg/a/a.go", "pkg/b/b b.go", "docs/empty.txt"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ path, want string }{
+		{"README.md", "# test\n"},
+		{"link.md", "README.md"},
+		{"pkg/b/b b.go", "package b\n"},
+		{"docs/empty.txt", ""},
+	}
+	for _, tt := range tests {
+		data, err := fs.ReadFile(fsys, tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("ReadFile(%q) = %q, want %q", tt.path, data, tt.want)
+		}
+	}
+	if _, err := fs.Stat(fsys, "sub"); err == nil {
+		t.Error("submodule is part of the file system")
+	}
+}
+
+func TestNewIndex(t *testing.T) {
+	dir := newTestRepo(t)
+	gittest.WriteFiles(t, dir, map[string]string{"README.md": "staged\n", "new.txt": "new\n"})
+	gittest.Git(t, dir, "add", "README.md", "new.txt")
+	gittest.WriteFiles(t, dir, map[string]string{"README.md": "not staged\n"})
+
+	fsys, err := NewIndex(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	for path, want := range map[string]string{"README.md": "staged\n", "new.txt": "new\n"} {
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("ReadFile(%q) = %q, want %q", path, data, want)
+		}
+		info, err := fs.Stat(fsys, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != int64(len(want)) {
+			t.Errorf("Stat(%q).Size() = %d, want %d", path, info.Size(), len(want))
+		}
+	}
+}
+
+func TestCatFileMissing(t *testing.T) {
+	dir := newTestRepo(t)
+	cat, err := startCatFile(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cat.close()
+
+	if _, err := cat.read("0000000000000000000000000000000000000000"); err == nil {
+		t.Error("read() of a missing object succeeded")
+	}
+	blob := gittest.Git(t, dir, "rev-parse", "HEAD:README.md")
+	data, err := cat.read(blob)
+	if err != nil {
+		t.Fatalf("read() after a missing object: %v", err)
+	}
+	if string(data) != "# test\n" {
+		t.Errorf("read() = %q, want %q", data, "# test\n")
+	}
+}
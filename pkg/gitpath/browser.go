@@ -0,0 +1,121 @@
+package gitpath
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// layout is the way a forge lays out the web URLs of repository contents.
+type layout int
+
+const (
+	layoutAny       layout = iota // unknown host: the GitHub and Gitea layouts
+	layoutGitHub                  // /org/repo/tree/main/pkg, /org/repo/blob/main/go.mod
+	layoutGitLab                  // /group/subgroup/repo/-/tree/main/pkg
+	layoutGitea                   // /org/repo/src/branch/main/pkg, also src/tag and src/commit
+	layoutBitbucket               // /org/repo/src/main/pkg
+)
+
+// forgeLayouts maps the hosts of well-known forges to their layout.
+var forgeLayouts = map[string]layout{
+	"github.com":    layoutGitHub,
+	"gitlab.com":    layoutGitLab,
+	"codeberg.org":  layoutGitea,
+	"gitea.com":     layoutGitea,
+	"bitbucket.org": layoutBitbucket,
+}
+
+// hostLayout returns the layout of the web URLs of the host.
+func hostLayout(host string) layout {
+	host = strings.ToLower(host)
+	if l, ok := forgeLayouts[host]; ok {
+		return l
+	}
+	if strings.Contains(host, "gitlab") {
+		return layoutGitLab
+	}
+	return layoutAny
+}
+
+// browserPath splits the path of a web URL pointing into a repository, e.g.
+// /org/repo/tree/main/pkg, into the repository path, the ref and the path
+// within the repository. The layout is chosen by the host, or by the /-/
+// marker of GitLab, so that a repository or group named like a marker of
+// another forge is not misread. Paths ending in .git are clone URLs. Refs
+// containing slashes cannot be told apart from the path, so the ref is
+// the first segment; see SplitRefPath for the refs of the remote.
+func browserPath(host, p string) (repoPath, ref, subpath string, ok bool) {
+	p = strings.Trim(p, "/")
+	if strings.HasSuffix(p, ".git") {
+		return "", "", "", false
+	}
+	s := strings.Split(p, "/")
+	split := func(repo, refAt int) (string, string, string, bool) {
+		if len(s) <= refAt {
+			return "", "", "", false
+		}
+		return strings.Join(s[:repo], "/"), s[refAt], strings.Join(s[refAt+1:], "/"), true
+	}
+
+	for i := 2; i+1 < len(s); i++ {
+		if s[i] == "-" && (s[i+1] == "tree" || s[i+1] == "blob") {
+			return split(i, i+2)
+		}
+	}
+	l := hostLayout(host)
+	if len(s) < 4 || l == layoutGitLab {
+		return "", "", "", false
+	}
+	switch {
+	case (s[2] == "tree" || s[2] == "blob") && (l == layoutGitHub || l == layoutAny):
+		return split(2, 3)
+	case s[2] == "src" && l == layoutBitbucket:
+		return split(2, 3)
+	case s[2] == "src" && (l == layoutGitea || l == layoutAny) &&
+		(s[3] == "branch" || s[3] == "tag" || s[3] == "commit"):
+		return split(2, 4)
+	}
+	return "", "", "", false
+}
+
+// setBrowserURL replaces a web URL pointing into a repository with the clone
+// URL of the repository, recording the ref and the path it pointed to.
+func (g *GitPath) setBrowserURL(u *url.URL) string {
+	repoPath, ref, subpath, ok := browserPath(u.Hostname(), u.Path)
+	if !ok {
+		return u.Path
+	}
+	clone := *u
+	clone.Path = "/" + strings.TrimSuffix(repoPath, ".git") + ".git"
+	clone.RawPath, clone.RawQuery, clone.Fragment = "", "", ""
+	g.Path, g.Ref, g.Subpath = clone.String(), ref, subpath
+	if subpath != "" && !commitHash.MatchString(ref) {
+		g.RefPath = ref + "/" + subpath
+	}
+	return repoPath
+}
+
+var commitHash = regexp.MustCompile(`^(?:[0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// SplitRefPath splits the ref and path of a web URL at the longest of the
+// refs it starts with, so that a branch such as release/2.3 is not mistaken
+// for the branch release and the directory 2.3. Without a match, or when
+// the ref of the URL was unambiguous, Ref and Subpath are returned.
+func (g *GitPath) SplitRefPath(refs []string) (ref, subpath string) {
+	ref, subpath = g.Ref, g.Subpath
+	if g.RefPath == "" {
+		return ref, subpath
+	}
+	for _, r := range refs {
+		if len(r) <= len(ref) {
+			continue
+		}
+		if r == g.RefPath {
+			ref, subpath = r, ""
+		} else if rest, ok := strings.CutPrefix(g.RefPath, r+"/"); ok {
+			ref, subpath = r, rest
+		}
+	}
+	return ref, subpath
+}
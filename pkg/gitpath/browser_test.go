@@ -0,0 +1,140 @@
+package gitpath
+
+import "testing"
+
+func TestBrowserPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		host, path  string
+		wantRepo    string
+		wantRef     string
+		wantSubpath string
+		wantOK      bool
+	}{
+		{"github tree", "github.com", "/org/repo/tree/main/pkg/server", "org/repo", "main", "pkg/server", true},
+		{"github blob", "github.com", "/org/repo/blob/v1.2/go.mod", "org/repo", "v1.2", "go.mod", true},
+		{"github ref only", "github.com", "/org/repo/tree/dev", "org/repo", "dev", "", true},
+		{"github repo", "github.com", "/org/repo", "", "", "", false},
+		{"github src is a path", "github.com", "/org/repo/src/branch/main", "", "", "", false},
+		{"gitlab tree", "gitlab.com", "/group/sub/repo/-/tree/main/pkg", "group/sub/repo", "main", "pkg", true},
+		{"gitlab blob", "gitlab.com", "/group/repo/-/blob/main/README.md", "group/repo", "main", "README.md", true},
+		{"gitlab subgroup named src", "gitlab.com", "/org/team/src/app", "", "", "", false},
+		{"gitlab subgroup named tree", "gitlab.com", "/org/tree/main/app", "", "", "", false},
+		{"self-hosted gitlab", "gitlab.example.com", "/org/team/src/app", "", "", "", false},
+		{"gitlab marker on any host", "git.example.com", "/org/repo/-/tree/main/docs", "org/repo", "main", "docs", true},
+		{"gitea branch", "codeberg.org", "/org/repo/src/branch/main/cmd", "org/repo", "main", "cmd", true},
+		{"gitea tag", "gitea.com", "/org/repo/src/tag/v1.0", "org/repo", "v1.0", "", true},
+		{"gitea commit", "codeberg.org", "/org/repo/src/commit/3f2c1a0/a.go", "org/repo", "3f2c1a0", "a.go", true},
+		{"gitea tree is a path", "codeberg.org", "/org/repo/tree/main/x", "", "", "", false},
+		{"bitbucket", "bitbucket.org", "/org/repo/src/main/lib", "org/repo", "main", "lib", true},
+		{"bitbucket branch is a ref", "bitbucket.org", "/org/repo/src/branch/lib", "org/repo", "branch", "lib", true},
+		{"unknown host github layout", "git.example.com", "/org/repo/blob/main/x.go", "org/repo", "main", "x.go", true},
+		{"unknown host gitea layout", "git.example.com", "/org/repo/src/branch/main", "org/repo", "main", "", true},
+		{"unknown host plain src", "git.example.com", "/org/repo/src/main", "", "", "", false},
+		{"clone url", "gitlab.com", "/org/team/src/app.git", "", "", "", false},
+		{"clone url on github", "github.com", "/org/repo/tree/x.git", "", "", "", false},
+		{"host case", "GitHub.com", "/org/repo/tree/main", "org/repo", "main", "", true},
+		{"github ref with slash", "github.com", "/org/repo/tree/release/2.3/pkg", "org/repo", "release", "2.3/pkg", true},
+		{"gitlab ref with slash", "gitlab.com", "/group/repo/-/tree/feature/x/docs", "group/repo", "feature", "x/docs", true},
+		{"gitea ref with slash", "codeberg.org", "/org/repo/src/branch/fix/a/b.go", "org/repo", "fix", "a/b.go", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, ref, sub, ok := browserPath(tt.host, tt.path)
+			if ok != tt.wantOK || repo != tt.wantRepo || ref != tt.wantRef || sub != tt.wantSubpath {
+				t.Errorf("browserPath(%q, %q) = %q, %q, %q, %v, want %q, %q, %q, %v", tt.host, tt.path,
+					repo, ref, sub, ok, tt.wantRepo, tt.wantRef, tt.wantSubpath, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFromURLBrowser(t *testing.T) {
+	tests := []struct {
+		url         string
+		wantPath    string
+		wantRef     string
+		wantSubpath string
+		wantRefPath string
+	}{
+		{
+			"https://github.com/org/repo/tree/main/pkg?tab=readme#top",
+			"https://github.com/org/repo.git", "main", "pkg", "main/pkg",
+		},
+		{
+			"https://github.com/org/repo/tree/main",
+			"https://github.com/org/repo.git", "main", "", "",
+		},
+		{
+			"https://gitlab.com/group/sub/repo/-/blob/dev/a/b.go",
+			"https://gitlab.com/group/sub/repo.git", "dev", "a/b.go", "dev/a/b.go",
+		},
+		{
+			"https://gitlab.com/org/team/src/app.git",
+			"https://gitlab.com/org/team/src/app.git", "", "", "",
+		},
+		{
+			"https://codeberg.org/org/repo/src/commit/0123456789abcdef0123456789abcdef01234567",
+			"https://codeberg.org/org/repo.git", "0123456789abcdef0123456789abcdef01234567", "", "",
+		},
+		{
+			"https://github.com/org/repo/blob/0123456789abcdef0123456789abcdef01234567/a/b.go",
+			"https://github.com/org/repo.git", "0123456789abcdef0123456789abcdef01234567", "a/b.go", "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			g, err := FromURL(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g.Path != tt.wantPath || g.Ref != tt.wantRef || g.Subpath != tt.wantSubpath || g.RefPath != tt.wantRefPath {
+				t.Errorf("FromURL(%q) = %s, %q, %q, %q, want %s, %q, %q, %q", tt.url,
+					g.Path, g.Ref, g.Subpath, g.RefPath, tt.wantPath, tt.wantRef, tt.wantSubpath, tt.wantRefPath)
+			}
+		})
+	}
+}
+
+func TestSplitRefPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		refs        []string
+		wantRef     string
+		wantSubpath string
+	}{
+		{"no refs", "https://github.com/org/repo/tree/release/2.3/pkg", nil, "release", "2.3/pkg"},
+		{"single segment ref", "https://github.com/org/repo/tree/main/pkg", []string{"main", "dev"}, "main", "pkg"},
+		{
+			"ref with slash", "https://github.com/org/repo/tree/release/2.3/pkg",
+			[]string{"main", "release", "release/2.3"}, "release/2.3", "pkg",
+		},
+		{
+			"longest ref wins", "https://github.com/org/repo/tree/a/b/c/d.go",
+			[]string{"a/b/c", "a/b", "a"}, "a/b/c", "d.go",
+		},
+		{"whole ref path is a ref", "https://github.com/org/repo/tree/feature/x", []string{"feature/x"}, "feature/x", ""},
+		{"prefix without slash", "https://github.com/org/repo/tree/release/2.3/pkg", []string{"release/2"}, "release", "2.3/pkg"},
+		{
+			"gitlab", "https://gitlab.com/group/repo/-/blob/feature/login/app/main.go",
+			[]string{"feature/login"}, "feature/login", "app/main.go",
+		},
+		{
+			"commit", "https://github.com/org/repo/tree/0123456789abcdef0123456789abcdef01234567/pkg",
+			[]string{"0123456789abcdef0123456789abcdef01234567/pkg"}, "0123456789abcdef0123456789abcdef01234567", "pkg",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := FromURL(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ref, sub := g.SplitRefPath(tt.refs)
+			if ref != tt.wantRef || sub != tt.wantSubpath {
+				t.Errorf("SplitRefPath(%q) = %q, %q, want %q, %q", tt.refs, ref, sub, tt.wantRef, tt.wantSubpath)
+			}
+		})
+	}
+}
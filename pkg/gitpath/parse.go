@@ -1,10 +1,29 @@
 package gitpath
 
+import "github.com/i-zaitsev/gitcat/pkg/log"
+
 // Parse returns git location parsed from the given string.
-// Supports both local and remote paths.
-func Parse(location string) (*GitPath, error) {
+// Supports local directories, archives and bundles, remote URLs and shorthand references.
+// A local directory takes precedence over an owner/repo shorthand of the
+// same name, and reading a location as such a shorthand is logged with a
+// warning; prefixed shorthands such as gh:owner/repo are never local.
+func Parse(location string, sh Shorthand) (*GitPath, error) {
+	if hasShorthandPrefix(location) {
+		return FromShorthand(location, sh)
+	}
 	if path, err := FromDir(location); err == nil {
 		return path, nil
 	}
+	if path, err := FromFile(location); err == nil {
+		return path, nil
+	}
+	if isShorthand(location) {
+		g, err := FromShorthand(location, sh)
+		if err == nil {
+			log.Warn("no local path named like the location, reading it as a shorthand reference",
+				"location", location, "url", g.Redacted())
+		}
+		return g, err
+	}
 	return FromURL(location)
 }
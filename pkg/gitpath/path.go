@@ -2,34 +2,86 @@ package gitpath
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
+	"github.com/i-zaitsev/gitcat/pkg/archive"
 	"github.com/i-zaitsev/gitcat/pkg/internal/utils"
 )
 
 const (
-	SSH   = "ssh"
-	HTTPS = "https"
-	Local = "local"
+	SSH     = "ssh"
+	HTTPS   = "https"
+	Git     = "git"
+	File    = "file"
+	Local   = "local"
+	Archive = "archive"
+	Bundle  = "bundle"
 )
 
-// GitPath represents a git repository URL with protocol information.
+// GitPath represents a git repository location with protocol information.
+// Path is the location as given by the user and is what git is invoked with;
+// the other fields are parsed from it.
 type GitPath struct {
-	Path string
-	Kind string
+	Path    string
+	Kind    string
+	Scheme  string // URL scheme, "ssh" for the scp-like form
+	User    string
+	Host    string
+	Port    string
+	Owner   string // user or namespace, including GitLab subgroups
+	Repo    string // repository name without the .git suffix
+	Ref     string // branch, tag or commit taken from a web URL or shorthand
+	Subpath string // directory or file within the repository taken from a web URL
+	RefPath string // ref and Subpath of a web URL when a ref with slashes cannot be told apart from the path
 }
 
 func FromDir(localDir string) (*GitPath, error) {
 	if !utils.DirExists(localDir) {
 		return nil, fmt.Errorf("directory %s does not exist", localDir)
 	}
-	return &GitPath{Path: localDir, Kind: Local}, nil
+	repo := filepath.Base(localDir)
+	if abs, err := filepath.Abs(localDir); err == nil {
+		repo = filepath.Base(abs)
+	}
+	return &GitPath{Path: localDir, Kind: Local, Repo: repo}, nil
+}
+
+// FromFile returns the location of a local archive (.tar, .tar.gz, .tgz, .zip)
+// or git bundle (.bundle).
+func FromFile(name string) (*GitPath, error) {
+	if !utils.FileExists(name) {
+		return nil, fmt.Errorf("file %s does not exist", name)
+	}
+	base := filepath.Base(name)
+	switch {
+	case archive.IsArchive(base):
+		return &GitPath{Path: name, Kind: Archive, Repo: archive.Name(base)}, nil
+	case strings.HasSuffix(base, ".bundle"):
+		return &GitPath{Path: name, Kind: Bundle, Repo: strings.TrimSuffix(base, ".bundle")}, nil
+	}
+	return nil, fmt.Errorf("unsupported file %s: must be an archive or a git bundle", name)
 }
 
 func (g *GitPath) IsLocal() bool {
 	return g.Kind == Local
 }
 
+// IsArchive reports whether the files are read from an archive rather than a git repository.
+func (g *GitPath) IsArchive() bool {
+	return g.Kind == Archive
+}
+
+// IsBundle reports whether the repository is cloned from a git bundle file.
+func (g *GitPath) IsBundle() bool {
+	return g.Kind == Bundle
+}
+
+// Redacted returns the path with any credentials embedded in it removed, for logging.
+func (g *GitPath) Redacted() string {
+	return Redact(g.Path)
+}
+
 // Extensions represent a list of file extensions to include in the output.
 type Extensions []string
 
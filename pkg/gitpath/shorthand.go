@@ -0,0 +1,81 @@
+package gitpath
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultHost is the host of owner/repo references without a prefix.
+const DefaultHost = "github.com"
+
+// shorthandHosts maps the prefixes of shorthand references to their hosts.
+var shorthandHosts = map[string]string{
+	"gh": "github.com",
+	"gl": "gitlab.com",
+}
+
+// Shorthand configures how owner/repo references are expanded.
+type Shorthand struct {
+	Host     string // host of references without a prefix, DefaultHost if empty
+	Protocol string // SSH or HTTPS, HTTPS if empty
+}
+
+// FromShorthand expands gh:owner/repo, gl:group/repo and owner/repo references,
+// each optionally followed by @ref, to the SSH or HTTPS URL of the repository.
+func FromShorthand(location string, sh Shorthand) (*GitPath, error) {
+	host := sh.Host
+	if host == "" {
+		host = DefaultHost
+	}
+	repoPath := location
+	if prefix, rest, ok := strings.Cut(location, ":"); ok {
+		if host, ok = shorthandHosts[prefix]; !ok {
+			return nil, fmt.Errorf("unknown shorthand prefix %q: must be one of gh, gl", prefix)
+		}
+		repoPath = rest
+	}
+	repoPath, ref, hasRef := strings.Cut(repoPath, "@")
+	if hasRef && ref == "" {
+		return nil, fmt.Errorf("invalid shorthand %q: empty ref", location)
+	}
+	if !strings.Contains(repoPath, "/") {
+		return nil, fmt.Errorf("invalid shorthand %q: must be owner/repo", location)
+	}
+
+	var rawURL string
+	switch sh.Protocol {
+	case "", HTTPS:
+		rawURL = "https://" + host + "/" + repoPath
+	case SSH:
+		rawURL = "git@" + host + ":" + repoPath
+	default:
+		return nil, fmt.Errorf("invalid protocol %q: must be one of https, ssh", sh.Protocol)
+	}
+	if !strings.HasSuffix(rawURL, ".git") {
+		rawURL += ".git"
+	}
+
+	g, err := FromURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shorthand %q: %w", location, err)
+	}
+	g.Ref = ref
+	return g, nil
+}
+
+// hasShorthandPrefix reports whether the location starts with a known prefix such as gh:.
+func hasShorthandPrefix(location string) bool {
+	prefix, _, ok := strings.Cut(location, ":")
+	_, known := shorthandHosts[prefix]
+	return ok && known
+}
+
+// unprefixedShorthand matches owner/repo and owner/repo@ref references.
+var unprefixedShorthand = regexp.MustCompile(`^[\w-][\w.-]*/[\w.-]+(@.+)?$`)
+
+// isShorthand reports whether the location has the form owner/repo rather
+// than that of a URL or a longer filesystem path.
+func isShorthand(location string) bool {
+	return unprefixedShorthand.MatchString(location)
+}
@@ -0,0 +1,94 @@
+package gitpath
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsShorthand(t *testing.T) {
+	tests := []struct {
+		location string
+		want     bool
+	}{
+		{"owner/repo", true},
+		{"owner/repo.go", true},
+		{"my-org/my_repo@v1.2", true},
+		{"owner/repo@feature/x", true},
+		{"owner", false},
+		{"owner/repo/sub", false},
+		{"./owner/repo", false},
+		{"../repo", false},
+		{".config/repo", false},
+		{"/abs/repo", false},
+		{"~/repo", false},
+		{`dir\repo`, false},
+		{"gh:owner/repo", false},
+		{"git@github.com:owner/repo", false},
+		{"https://github.com/owner/repo", false},
+		{"owner/re po", false},
+		{"owner/repo@", false},
+	}
+	for _, tt := range tests {
+		if got := isShorthand(tt.location); got != tt.want {
+			t.Errorf("isShorthand(%q) = %v, want %v", tt.location, got, tt.want)
+		}
+	}
+}
+
+func TestFromShorthand(t *testing.T) {
+	tests := []struct {
+		location string
+		sh       Shorthand
+		wantPath string
+		wantRef  string
+	}{
+		{"owner/repo", Shorthand{}, "https://github.com/owner/repo.git", ""},
+		{"owner/repo@v1", Shorthand{}, "https://github.com/owner/repo.git", "v1"},
+		{"owner/repo", Shorthand{Host: "git.example.com", Protocol: SSH}, "git@git.example.com:owner/repo.git", ""},
+		{"gh:owner/repo.git", Shorthand{Host: "git.example.com"}, "https://github.com/owner/repo.git", ""},
+		{"gl:group/sub/repo@main", Shorthand{}, "https://gitlab.com/group/sub/repo.git", "main"},
+	}
+	for _, tt := range tests {
+		g, err := FromShorthand(tt.location, tt.sh)
+		if err != nil {
+			t.Fatalf("FromShorthand(%q) error: %v", tt.location, err)
+		}
+		if g.Path != tt.wantPath || g.Ref != tt.wantRef {
+			t.Errorf("FromShorthand(%q) = %s@%s, want %s@%s", tt.location, g.Path, g.Ref, tt.wantPath, tt.wantRef)
+		}
+	}
+
+	for _, location := range []string{"xx:owner/repo", "owner@v1", "owner/repo@", "owner/../repo"} {
+		if _, err := FromShorthand(location, Shorthand{}); err == nil {
+			t.Errorf("FromShorthand(%q) succeeded, want an error", location)
+		}
+	}
+}
+
+func TestParseShorthandOrPath(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("owner/local", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		location string
+		wantKind string
+		wantErr  bool
+	}{
+		{"owner/local", Local, false},
+		{"gh:owner/local", HTTPS, false},
+		{"owner/remote", HTTPS, false},
+		{"owner/local/missing", "", true},
+		{"./owner/remote", "", true},
+	}
+	for _, tt := range tests {
+		g, err := Parse(tt.location, Shorthand{})
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.location, err, tt.wantErr)
+		}
+		if err == nil && g.Kind != tt.wantKind {
+			t.Errorf("Parse(%q) kind = %s, want %s", tt.location, g.Kind, tt.wantKind)
+		}
+	}
+}
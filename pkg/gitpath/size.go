@@ -8,11 +8,15 @@ import (
 // Size represents a file size in bytes, parsed from KB values.
 type Size int64
 
+// String returns the size in KB, the unit Set parses, or -1 for
+// a negative size, which disables the limit.
 func (s *Size) String() string {
 	if s == nil {
 		return "0"
+	} else if *s < 0 {
+		return "-1"
 	}
-	return fmt.Sprintf("%d", *s)
+	return fmt.Sprintf("%d", *s/1024)
 }
 
 func (s *Size) Set(value string) error {
@@ -0,0 +1,67 @@
+// Package gittest creates git repositories for tests.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Isolate makes git ignore the user and system configuration and commit
+// with a fixed identity. Tests are skipped when git is not installed.
+func Isolate(t testing.TB) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+}
+
+// Git runs git in dir and returns its trimmed output.
+func Git(t testing.TB, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// WriteFiles writes the files, keyed by slash-separated path, under dir.
+func WriteFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// NewRepo creates a repository on the main branch with a commit for each
+// set of files, and returns its directory.
+func NewRepo(t testing.TB, commits ...map[string]string) string {
+	t.Helper()
+	Isolate(t)
+	dir := t.TempDir()
+	Git(t, dir, "init", "-q", "-b", "main")
+	for i, files := range commits {
+		WriteFiles(t, dir, files)
+		Git(t, dir, "add", "-A")
+		Git(t, dir, "commit", "-q", "-m", "commit "+strconv.Itoa(i+1))
+	}
+	return dir
+}
@@ -0,0 +1,170 @@
+// Package memfs implements a read-only file system whose tree is kept in
+// memory and whose file contents are loaded on demand.
+package memfs
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FS is a read-only in-memory file system. Parent directories of the
+// added files are created implicitly.
+type FS struct {
+	files map[string]*file
+	dirs  map[string][]fs.DirEntry
+}
+
+// file implements fs.FileInfo for files and directories.
+type file struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	load    func() ([]byte, error)
+}
+
+// New returns an empty file system.
+func New() *FS {
+	return &FS{
+		files: make(map[string]*file),
+		dirs:  map[string][]fs.DirEntry{".": nil},
+	}
+}
+
+// Add adds a file whose content is returned by load when it is opened.
+// Adding a name twice replaces the earlier file.
+func (m *FS) Add(name string, size int64, mode fs.FileMode, modTime time.Time, load func() ([]byte, error)) {
+	f := &file{name: path.Base(name), size: size, mode: mode, modTime: modTime, load: load}
+	if _, ok := m.files[name]; ok {
+		m.files[name] = f
+		entries := m.dirs[path.Dir(name)]
+		if i := slices.IndexFunc(entries, func(e fs.DirEntry) bool { return e.Name() == f.name }); i >= 0 {
+			entries[i] = fs.FileInfoToDirEntry(f)
+		}
+		return
+	}
+	m.files[name] = f
+
+	var entry fs.DirEntry = fs.FileInfoToDirEntry(f)
+	for dir := path.Dir(name); ; dir = path.Dir(dir) {
+		_, exists := m.dirs[dir]
+		m.dirs[dir] = append(m.dirs[dir], entry)
+		if exists || dir == "." {
+			return
+		}
+		entry = fs.FileInfoToDirEntry(&file{name: path.Base(dir), mode: fs.ModeDir | 0755})
+	}
+}
+
+// Bytes returns a loader of fixed content for Add.
+func Bytes(data []byte) func() ([]byte, error) {
+	return func() ([]byte, error) { return data, nil }
+}
+
+// Open implements fs.FS.
+func (m *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if f, ok := m.files[name]; ok {
+		data, err := f.load()
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return &openFile{info: f, Reader: bytes.NewReader(data)}, nil
+	}
+	if _, ok := m.dirs[name]; ok {
+		entries, _ := m.ReadDir(name)
+		return &openDir{info: &file{name: path.Base(name), mode: fs.ModeDir | 0755}, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Stat implements fs.StatFS without loading the file content.
+func (m *FS) Stat(name string) (fs.FileInfo, error) {
+	if f, ok := m.files[name]; ok {
+		return f, nil
+	}
+	if _, ok := m.dirs[name]; ok {
+		return &file{name: path.Base(name), mode: fs.ModeDir | 0755}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// Lstat implements fs.ReadLinkFS. Symbolic links are never followed, so it is the same as Stat.
+func (m *FS) Lstat(name string) (fs.FileInfo, error) {
+	return m.Stat(name)
+}
+
+// ReadLink implements fs.ReadLinkFS. The target of a symbolic link is its content.
+func (m *FS) ReadLink(name string) (string, error) {
+	f, ok := m.files[name]
+	if !ok || f.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	data, err := f.load()
+	if err != nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: err}
+	}
+	return string(data), nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (m *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, ok := m.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries = slices.Clone(entries)
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+func (f *file) Name() string       { return f.name }
+func (f *file) Size() int64        { return f.size }
+func (f *file) Mode() fs.FileMode  { return f.mode }
+func (f *file) ModTime() time.Time { return f.modTime }
+func (f *file) IsDir() bool        { return f.mode.IsDir() }
+func (f *file) Sys() any           { return nil }
+
+type openFile struct {
+	info *file
+	*bytes.Reader
+}
+
+func (f *openFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *openFile) Close() error               { return nil }
+
+type openDir struct {
+	info    *file
+	entries []fs.DirEntry
+}
+
+func (d *openDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *openDir) Close() error               { return nil }
+
+func (d *openDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *openDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
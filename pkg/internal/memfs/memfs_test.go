@@ -0,0 +1,57 @@
+package memfs
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFS(t *testing.T) {
+	m := New()
+	m.Add("README.md", 6, 0644, time.Time{}, Bytes([]byte("readme")))
+	m.Add("pkg/a/a.go", 1, 0644, time.Time{}, Bytes([]byte("a")))
+	m.Add("pkg/b.go", 1, 0755, time.Time{}, Bytes([]byte("b")))
+
+	if err := fstest.TestFS(m, "README.md", "pkg/a/a.go", "pkg/b.go"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddReplace(t *testing.T) {
+	m := New()
+	m.Add("pkg/a.go", 3, 0644, time.Time{}, Bytes([]byte("old")))
+	m.Add("pkg/a.go", 7, 0755, time.Time{}, Bytes([]byte("changed")))
+
+	entries, err := m.ReadDir("pkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("ReadDir() = %v, want one entry", entries)
+	}
+	info, err := entries[0].Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 7 || info.Mode() != 0755 {
+		t.Errorf("entry after replace: size %d, mode %v, want 7, %v", info.Size(), info.Mode(), fs.FileMode(0755))
+	}
+	if err := fstest.TestFS(m, "pkg/a.go"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadLink(t *testing.T) {
+	m := New()
+	m.Add("link", 6, fs.ModeSymlink|0777, time.Time{}, Bytes([]byte("target")))
+	m.Add("file", 1, 0644, time.Time{}, Bytes([]byte("x")))
+
+	if target, err := m.ReadLink("link"); err != nil || target != "target" {
+		t.Errorf("ReadLink(link) = %q, %v, want %q", target, err, "target")
+	}
+	if _, err := m.ReadLink("file"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("ReadLink(file) error = %v, want %v", err, fs.ErrInvalid)
+	}
+}
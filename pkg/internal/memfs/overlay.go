@@ -0,0 +1,41 @@
+package memfs
+
+import (
+	"io"
+	"io/fs"
+)
+
+// Overlay returns a file system serving the files of top in place of
+// the same files of base. Closing it closes base if it is an io.Closer.
+func Overlay(base fs.FS, top *FS) fs.FS {
+	return &overlayFS{base: base, top: top}
+}
+
+type overlayFS struct {
+	base fs.FS
+	top  *FS
+}
+
+// Open implements fs.FS.
+func (o *overlayFS) Open(name string) (fs.File, error) {
+	if f, ok := o.top.files[name]; ok && !f.IsDir() {
+		return o.top.Open(name)
+	}
+	return o.base.Open(name)
+}
+
+// Stat implements fs.StatFS.
+func (o *overlayFS) Stat(name string) (fs.FileInfo, error) {
+	if f, ok := o.top.files[name]; ok && !f.IsDir() {
+		return f, nil
+	}
+	return fs.Stat(o.base, name)
+}
+
+// Close implements io.Closer.
+func (o *overlayFS) Close() error {
+	if c, ok := o.base.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
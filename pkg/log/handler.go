@@ -14,6 +14,7 @@ type colorWriter struct {
 }
 
 // NewColorWriter creates a writer that colorizes entire log lines.
+// Each line is written above the progress status line, if one is drawn.
 func NewColorWriter(out io.Writer) io.Writer {
 	return &colorWriter{out: out}
 }
@@ -25,7 +26,10 @@ func (w *colorWriter) Write(p []byte) (n int, err error) {
 	if len(line) > 0 && line[len(line)-1] == '\n' {
 		colored += "\n"
 	}
-	return w.out.Write([]byte(colored))
+	if _, err := writeAboveStatus(w.out, []byte(colored)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
 }
 
 func extractLevel(line string) slog.Level {
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/i-zaitsev/gitcat/pkg/termcolor"
+)
+
+// progressInterval is the minimum time between progress log records
+// when stderr is not a terminal.
+const progressInterval = 5 * time.Second
+
+// Progress reports the state of a long-running operation.
+// On a terminal it redraws a single status line on stderr; otherwise
+// it emits a structured log record at most once per progressInterval.
+type Progress struct {
+	msg  string
+	tty  bool
+	out  io.Writer
+	last time.Time
+	mu   sync.Mutex
+}
+
+// statusLine is the terminal line shared by every Progress. Operations
+// running at the same time take turns drawing their latest status on it,
+// and log records are written above it, see writeAboveStatus.
+var statusLine struct {
+	mu    sync.Mutex
+	owner *Progress // progress whose status is drawn, nil when the line is clear
+	text  string
+}
+
+// NewProgress creates a progress reporter for the operation described by msg.
+func NewProgress(msg string) *Progress {
+	return &Progress{
+		msg:  msg,
+		tty:  termcolor.IsTerminal(),
+		out:  os.Stderr,
+		last: time.Now(),
+	}
+}
+
+// Update reports the current status. The status text is shown on the
+// terminal line, and args are the attributes of the structured log record.
+func (p *Progress) Update(status string, args ...any) {
+	if p.tty {
+		statusLine.mu.Lock()
+		defer statusLine.mu.Unlock()
+		statusLine.owner, statusLine.text = p, fmt.Sprintf("%s: %s", p.msg, status)
+		_, _ = fmt.Fprint(p.out, "\r\033[K"+statusLine.text)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now := time.Now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		Info(p.msg, args...)
+	}
+}
+
+// Done clears the terminal status line if it shows the status of p.
+func (p *Progress) Done() {
+	statusLine.mu.Lock()
+	defer statusLine.mu.Unlock()
+
+	if statusLine.owner == p {
+		_, _ = fmt.Fprint(p.out, "\r\033[K")
+		statusLine.owner, statusLine.text = nil, ""
+	}
+}
+
+// writeAboveStatus writes a log record to out, clearing the status line
+// first and drawing it again below the record.
+func writeAboveStatus(out io.Writer, record []byte) (int, error) {
+	statusLine.mu.Lock()
+	defer statusLine.mu.Unlock()
+
+	owner := statusLine.owner
+	if owner != nil {
+		_, _ = fmt.Fprint(owner.out, "\r\033[K")
+	}
+	n, err := out.Write(record)
+	if owner != nil {
+		_, _ = fmt.Fprint(owner.out, statusLine.text)
+	}
+	return n, err
+}
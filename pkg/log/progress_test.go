@@ -0,0 +1,58 @@
+package log
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func newTestProgress(msg string, out *bytes.Buffer) *Progress {
+	return &Progress{msg: msg, tty: true, out: out}
+}
+
+func TestProgressSharedLine(t *testing.T) {
+	var out bytes.Buffer
+	a := newTestProgress("cloning a", &out)
+	b := newTestProgress("cloning b", &out)
+
+	a.Update("10%")
+	b.Update("20%")
+	a.Done() // b owns the line, so it stays
+	b.Update("30%")
+	b.Done()
+
+	want := "\r\033[Kcloning a: 10%" +
+		"\r\033[Kcloning b: 20%" +
+		"\r\033[Kcloning b: 30%" +
+		"\r\033[K"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestLogAboveProgress(t *testing.T) {
+	defer SetLogger(logger)
+	var out bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(NewColorWriter(&out), &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+
+	p := newTestProgress("cloning", &out)
+	p.Update("50%")
+	Warn("slow remote")
+	p.Done()
+	Info("done")
+
+	want := "\r\033[Kcloning: 50%" +
+		"\r\033[K" + "level=WARN msg=\"slow remote\"\n" + "cloning: 50%" +
+		"\r\033[K" +
+		"level=INFO msg=done\n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,132 @@
+package ls
+
+import (
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// RepoContent is the list of selected files of a repository and the
+// file system they are read from. File names are slash-separated and
+// relative to the root of the file system.
+type RepoContent struct {
+	Root   string // where the files come from, e.g. a directory or an archive
+	GitDir string // directory of the git repository the files come from, if any
+	Files  []string
+	FS     fs.FS
+	Attrs  map[string]Attrs // metadata of the files that have any
+	// mounts maps the name prefixes of combined repositories to their content.
+	mounts map[string]*RepoContent
+}
+
+// Attrs holds metadata about a file beyond its content.
+type Attrs struct {
+	Status    string   // changes against HEAD of a local repository, e.g. "staged,modified"
+	Submodule string   // path and commit of the submodule the file belongs to, e.g. "lib@3f2c1a0..."
+	Tags      []string // kinds of code the file was classified as, e.g. "generated" or "vendored"
+}
+
+// Tags of the kinds of code a file can be classified as.
+const (
+	TagGenerated     = "generated"
+	TagVendored      = "vendored"
+	TagDocumentation = "documentation"
+)
+
+// Attr returns the metadata of a file.
+func (r *RepoContent) Attr(name string) Attrs {
+	return r.Attrs[name]
+}
+
+// setAttr updates the metadata of a file.
+func (r *RepoContent) setAttr(name string, update func(a *Attrs)) {
+	if r.Attrs == nil {
+		r.Attrs = make(map[string]Attrs)
+	}
+	a := r.Attrs[name]
+	update(&a)
+	r.Attrs[name] = a
+}
+
+// Tag adds a tag to the metadata of a file, if it does not have it yet.
+func (r *RepoContent) Tag(name, tag string) {
+	r.setAttr(name, func(a *Attrs) {
+		if !slices.Contains(a.Tags, tag) {
+			a.Tags = append(slices.Clone(a.Tags), tag)
+		}
+	})
+}
+
+// Combine merges several repositories into one whose file names are prefixed
+// with the name of the repository they belong to, e.g. repoA/pkg/x.go.
+func Combine(names []string, repos []*RepoContent) *RepoContent {
+	mounts := make(mountFS, len(repos))
+	combined := RepoContent{
+		FS:     mounts,
+		mounts: make(map[string]*RepoContent, len(repos)),
+	}
+	for i, repo := range repos {
+		mounts[names[i]] = repo.FS
+		combined.mounts[names[i]] = repo
+		for _, f := range repo.Files {
+			combined.Files = append(combined.Files, names[i]+"/"+f)
+		}
+		for f, attrs := range repo.Attrs {
+			combined.setAttr(names[i]+"/"+f, func(a *Attrs) { *a = attrs })
+		}
+	}
+	return &combined
+}
+
+// Resolve returns the repository a file belongs to and the path of the file
+// relative to it. Only combined repositories resolve to another repository.
+func (r *RepoContent) Resolve(name string) (*RepoContent, string) {
+	if prefix, rel, ok := mountOf(r.mounts, name); ok {
+		return r.mounts[prefix].Resolve(rel)
+	}
+	return r, name
+}
+
+// WithFiles returns a copy of the content that lists only the given files.
+// The metadata is copied too, so that tagging the files of the copy leaves
+// the original unchanged.
+func (r *RepoContent) WithFiles(files []string) *RepoContent {
+	subset := *r
+	subset.Files = files
+	subset.Attrs = maps.Clone(r.Attrs)
+	return &subset
+}
+
+// mountFS presents several file systems as directories of a single one.
+// Only the mounted file systems can be opened, not the directories above them.
+type mountFS map[string]fs.FS
+
+// Open implements fs.FS.
+func (m mountFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if prefix, rel, ok := mountOf(m, name); ok {
+		return m[prefix].Open(rel)
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// mountOf returns the mount point containing name and the path of name below
+// it. Names of combined repositories may be nested, e.g. org and org/repo, so
+// the longest matching mount point is chosen.
+func mountOf[T any](mounts map[string]T, name string) (prefix, rel string, ok bool) {
+	for p := range mounts {
+		if (name == p || strings.HasPrefix(name, p+"/")) && (!ok || len(p) > len(prefix)) {
+			prefix, ok = p, true
+		}
+	}
+	if !ok {
+		return "", "", false
+	}
+	if rel = strings.TrimPrefix(name[len(prefix):], "/"); rel == "" {
+		rel = "."
+	}
+	return prefix, rel, true
+}
@@ -0,0 +1,97 @@
+package ls
+
+import (
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+// nestedRepos combines repositories whose names are nested: org and org/x.
+func nestedRepos() (*RepoContent, []*RepoContent) {
+	repos := []*RepoContent{
+		{Root: "org", Files: []string{"y/a.go", "README.md"}, FS: fstest.MapFS{
+			"y/a.go":    {Data: []byte("org")},
+			"README.md": {Data: []byte("org readme")},
+		}},
+		{Root: "org/x", Files: []string{"a.go"}, FS: fstest.MapFS{
+			"a.go": {Data: []byte("org/x")},
+		}},
+		{Root: "other", Files: []string{"a.go"}, FS: fstest.MapFS{
+			"a.go": {Data: []byte("other")},
+		}},
+	}
+	return Combine([]string{"org", "org/x", "other"}, repos), repos
+}
+
+func TestCombineResolve(t *testing.T) {
+	combined, repos := nestedRepos()
+
+	tests := []struct {
+		name     string
+		wantRepo *RepoContent
+		wantRel  string
+	}{
+		{"org/README.md", repos[0], "README.md"},
+		{"org/y/a.go", repos[0], "y/a.go"},
+		{"org/x/a.go", repos[1], "a.go"},
+		{"other/a.go", repos[2], "a.go"},
+		{"missing/a.go", combined, "missing/a.go"},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			repo, rel := combined.Resolve(tt.name)
+			if repo != tt.wantRepo || rel != tt.wantRel {
+				t.Fatalf("Resolve(%q) = %s, %q, want %s, %q", tt.name, repo.Root, rel, tt.wantRepo.Root, tt.wantRel)
+			}
+		}
+	}
+}
+
+func TestCombineOpen(t *testing.T) {
+	combined, _ := nestedRepos()
+
+	want := []string{"org/y/a.go", "org/README.md", "org/x/a.go", "other/a.go"}
+	if !slices.Equal(combined.Files, want) {
+		t.Errorf("Files = %q, want %q", combined.Files, want)
+	}
+	tests := map[string]string{
+		"org/README.md": "org readme",
+		"org/y/a.go":    "org",
+		"org/x/a.go":    "org/x",
+		"other/a.go":    "other",
+	}
+	for name, wantData := range tests {
+		for range 20 {
+			data, err := fs.ReadFile(combined.FS, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != wantData {
+				t.Fatalf("ReadFile(%q) = %q, want %q", name, data, wantData)
+			}
+		}
+	}
+	if _, err := combined.FS.Open("missing/a.go"); err == nil {
+		t.Error("Open of an unmounted name succeeded")
+	}
+}
+
+func TestWithFiles(t *testing.T) {
+	content := &RepoContent{Files: []string{"a.go", "b.go"}}
+	content.Tag("a.go", TagGenerated)
+
+	subset := content.WithFiles([]string{"a.go"})
+	subset.Tag("a.go", TagVendored)
+	subset.Tag("b.go", TagVendored)
+
+	if got := content.Attr("a.go").Tags; !slices.Equal(got, []string{TagGenerated}) {
+		t.Errorf("tags of the original a.go = %q, want %q", got, []string{TagGenerated})
+	}
+	if got := content.Attr("b.go").Tags; got != nil {
+		t.Errorf("tags of the original b.go = %q, want none", got)
+	}
+	if got := subset.Attr("a.go").Tags; !slices.Equal(got, []string{TagGenerated, TagVendored}) {
+		t.Errorf("tags of the subset a.go = %q, want %q", got, []string{TagGenerated, TagVendored})
+	}
+}
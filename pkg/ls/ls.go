@@ -1,26 +1,46 @@
 package ls
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/i-zaitsev/gitcat/pkg/archive"
+	"github.com/i-zaitsev/gitcat/pkg/gitattr"
+	"github.com/i-zaitsev/gitcat/pkg/gitcache"
 	"github.com/i-zaitsev/gitcat/pkg/gitclone"
+	"github.com/i-zaitsev/gitcat/pkg/gitfs"
 	"github.com/i-zaitsev/gitcat/pkg/gitpath"
+	"github.com/i-zaitsev/gitcat/pkg/internal/memfs"
 	"github.com/i-zaitsev/gitcat/pkg/log"
 )
 
-type RepoContent struct {
-	Root  string
-	Files []string
-}
-
 type List struct {
 	dotIgnore    bool
 	includePaths []string
 	excludePaths []string
+	cloneOpts    gitclone.Options
+	cache        *gitcache.Cache
+	rev          string
+	index        bool
+	symlinks     Symlinks
+	keepMarked   []string
+}
+
+// markedAttrs are the .gitattributes attributes whose files are skipped
+// unless they are kept with KeepMarked.
+var markedAttrs = []string{gitattr.Generated, gitattr.Vendored, gitattr.Documentation, gitattr.ExportIgnore}
+
+// markedTags are the tags of the kept files marked with the attributes.
+var markedTags = map[string]string{
+	gitattr.Generated:     TagGenerated,
+	gitattr.Vendored:      TagVendored,
+	gitattr.Documentation: TagDocumentation,
 }
 
 func NewList() *List {
@@ -44,23 +64,76 @@ func (l *List) ExcludePaths(paths ...string) *List {
 	return l
 }
 
-func (l *List) RemoteRepo(repoUrl *gitpath.GitPath, cloneDir string) (*RepoContent, error) {
-	if err := gitclone.Clone(repoUrl, cloneDir); err != nil {
+// CloneOptions configures how remote repositories are cloned.
+func (l *List) CloneOptions(opts gitclone.Options) *List {
+	l.cloneOpts = opts
+	return l
+}
+
+// UseCache configures remote repositories to be cloned from mirrors kept in the cache.
+func (l *List) UseCache(cache *gitcache.Cache) *List {
+	l.cache = cache
+	return l
+}
+
+// FromRev configures the list to read the files of the given commit from the
+// object database instead of the working tree. Remote repositories are then
+// cloned without a checkout. The FS of the returned content must be closed.
+func (l *List) FromRev(rev string) *List {
+	l.rev = rev
+	return l
+}
+
+// FromIndex configures the list to read the staged files of local
+// repositories from the index instead of the working tree.
+// The FS of the returned content must be closed.
+func (l *List) FromIndex() *List {
+	l.index = true
+	return l
+}
+
+// WithSymlinks configures how symbolic links are listed, recording them by default.
+func (l *List) WithSymlinks(policy Symlinks) *List {
+	l.symlinks = policy
+	return l
+}
+
+// KeepMarked configures the list to keep the files that .gitattributes marks
+// with the given attributes, e.g. gitattr.Generated. Files marked generated,
+// vendored, documentation or export-ignore are skipped otherwise.
+func (l *List) KeepMarked(attrs ...string) *List {
+	l.keepMarked = attrs
+	return l
+}
+
+func (l *List) RemoteRepo(ctx context.Context, repoUrl *gitpath.GitPath, cloneDir string) (*RepoContent, error) {
+	if err := l.clone(ctx, repoUrl, cloneDir); err != nil {
 		return nil, err
 	}
 
-	if _, err := gitclone.Status(cloneDir); err != nil {
-		return nil, fmt.Errorf("failed to get status: %w", err)
+	if l.rev != "" {
+		return l.GitTree(ctx, cloneDir, l.rev)
 	}
 
-	if content, err := l.walkGitRepo(cloneDir); err != nil {
-		return nil, err
-	} else {
-		return content, nil
+	return l.workTree(ctx, cloneDir, l.cloneOpts.Submodules)
+}
+
+// clone clones the repository into cloneDir, either directly or from a cached mirror.
+func (l *List) clone(ctx context.Context, repoUrl *gitpath.GitPath, cloneDir string) error {
+	opts := l.cloneOpts
+	opts.NoCheckout = l.rev != ""
+	if l.cache != nil {
+		mirror, unlock, err := l.cache.Mirror(ctx, repoUrl, opts.Auth)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		opts.Mirror = mirror
 	}
+	return gitclone.Clone(ctx, repoUrl, cloneDir, opts)
 }
 
-func (l *List) LocalRepo(repoDir string) (*RepoContent, error) {
+func (l *List) LocalRepo(ctx context.Context, repoDir string) (*RepoContent, error) {
 	state, err := os.Stat(repoDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat directory: %w", err)
@@ -68,7 +141,180 @@ func (l *List) LocalRepo(repoDir string) (*RepoContent, error) {
 	if !state.IsDir() {
 		return nil, fmt.Errorf("not a directory: %s", repoDir)
 	}
-	return l.walkGitRepo(repoDir)
+
+	var content *RepoContent
+	switch {
+	case l.index:
+		content, err = l.gitFS(ctx, repoDir, "index", func() (*gitfs.FS, error) {
+			return gitfs.NewIndex(ctx, repoDir)
+		})
+	case l.rev != "":
+		content, err = l.GitTree(ctx, repoDir, l.rev)
+	default:
+		content, err = l.workTree(ctx, repoDir, l.localSubmodules())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if l.rev == "" || l.rev == "HEAD" {
+		l.addStatus(ctx, repoDir, content)
+	}
+
+	return content, nil
+}
+
+// localSubmodules returns the submodule mode of a local repository, in which
+// the submodules that are checked out are listed unless the mode is set.
+func (l *List) localSubmodules() gitclone.Submodules {
+	if l.cloneOpts.Submodules == "" {
+		return gitclone.SubmodulesRecursive
+	}
+	return l.cloneOpts.Submodules
+}
+
+// workTree walks the working tree of a repository and applies the submodule
+// mode: files of the submodules that are not included are dropped, and the
+// others are tagged with their submodule.
+func (l *List) workTree(ctx context.Context, repoDir string, mode gitclone.Submodules) (*RepoContent, error) {
+	content, err := l.Walk(ctx, os.DirFS(repoDir), repoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !gitclone.IsRepository(ctx, repoDir) {
+		log.Debug("not a git repository", "dir", repoDir)
+		return content, nil
+	}
+	content.GitDir = repoDir
+
+	modules, err := gitclone.ListSubmodules(ctx, repoDir)
+	if err != nil {
+		log.Warn("failed to list submodules", "dir", repoDir, "error", err)
+		return content, nil
+	}
+	if len(modules) == 0 {
+		return content, nil
+	}
+
+	for _, m := range modules {
+		if !mode.Includes(m.Depth) {
+			log.Info("skipping submodule", "path", m.Path, "commit", m.Commit, "mode", mode.String())
+		} else if !m.Initialized {
+			log.Warn("submodule is not checked out", "path", m.Path, "commit", m.Commit)
+		}
+	}
+
+	files := content.Files[:0]
+	for _, f := range content.Files {
+		m, ok := containingSubmodule(modules, f)
+		if !ok {
+			files = append(files, f)
+			continue
+		}
+		if !mode.Includes(m.Depth) {
+			continue
+		}
+		files = append(files, f)
+		content.setAttr(f, func(a *Attrs) { a.Submodule = m.String() })
+	}
+	content.Files = files
+	return content, nil
+}
+
+// containingSubmodule returns the innermost submodule containing the file.
+// Files of a nested submodule belong to the nested one, not to its parent.
+func containingSubmodule(modules []gitclone.Submodule, name string) (gitclone.Submodule, bool) {
+	var found gitclone.Submodule
+	for _, m := range modules {
+		if strings.HasPrefix(name, m.Path+"/") && len(m.Path) > len(found.Path) {
+			found = m
+		}
+	}
+	return found, found.Path != ""
+}
+
+// addStatus records which files of a local repository differ from HEAD.
+// Directories that are not git repositories have no status.
+func (l *List) addStatus(ctx context.Context, repoDir string, content *RepoContent) {
+	changes, err := gitclone.Status(ctx, repoDir)
+	if err != nil {
+		log.Debug("no git status for directory", "dir", repoDir, "error", err)
+		return
+	}
+	for _, f := range content.Files {
+		if change, ok := changes[f]; ok {
+			content.setAttr(f, func(a *Attrs) { a.Status = change.String() })
+		}
+	}
+	log.Debug("added git status", "changed", len(changes))
+}
+
+// GitTree lists the files of the commit rev of the repository in repoDir,
+// reading them from its object database. The FS of the returned content
+// must be closed.
+func (l *List) GitTree(ctx context.Context, repoDir, rev string) (*RepoContent, error) {
+	return l.gitFS(ctx, repoDir, rev, func() (*gitfs.FS, error) {
+		return gitfs.New(ctx, repoDir, rev)
+	})
+}
+
+// gitFS walks a file system read from the git objects of the repository in
+// repoDir, the index or a commit named by source, closing it on failure.
+func (l *List) gitFS(ctx context.Context, repoDir, source string, open func() (*gitfs.FS, error)) (*RepoContent, error) {
+	root := repoDir + "@" + source
+	if l.cloneOpts.Submodules.Includes(1) {
+		log.Warn("submodules are only listed from the working tree", "root", root)
+	}
+	fsys, err := open()
+	if err != nil {
+		return nil, err
+	}
+	content, err := l.Walk(ctx, fsys, root)
+	if err != nil {
+		_ = fsys.Close()
+		return nil, err
+	}
+	content.GitDir = repoDir
+	return content, nil
+}
+
+// Archive lists the files of a .tar, .tar.gz, .tgz or .zip archive
+// without extracting it.
+func (l *List) Archive(ctx context.Context, name string) (*RepoContent, error) {
+	fsys, err := archive.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return l.Walk(ctx, fsys, name)
+}
+
+// include applies the dot-file and path filters to a walked entry.
+// Directories that are filtered out are reported with fs.SkipDir.
+func (l *List) include(relPath string, isDir bool) (bool, error) {
+	isDotFile := l.dotIgnore && strings.HasPrefix(path.Base(relPath), ".")
+
+	if isDir {
+		if isDotFile {
+			log.Debug("skipping dot directory", "dir", relPath)
+			return false, fs.SkipDir
+		}
+		if !l.shouldIncludePath(relPath, true) {
+			log.Debug("skipping filtered directory", "dir", relPath)
+			return false, fs.SkipDir
+		}
+		return false, nil
+	}
+
+	if isDotFile {
+		log.Debug("skipping dot file", "file", relPath)
+		return false, nil
+	}
+	if !l.shouldIncludePath(relPath, false) {
+		log.Debug("skipping filtered file", "file", relPath)
+		return false, nil
+	}
+	return true, nil
 }
 
 // shouldIncludePath determines if a relative path should be included based on
@@ -97,54 +343,185 @@ func (l *List) shouldIncludePath(relPath string, isDirectory bool) bool {
 	return false
 }
 
-func (l *List) walkGitRepo(repoDir string) (*RepoContent, error) {
-	log.Debug("walking repository directory", "dir", repoDir)
-	content := RepoContent{
-		Root: repoDir,
+// Walk lists the files of fsys that pass the dot-file and path filters and
+// are not skipped for their .gitattributes, applying the symlink policy to
+// the links among them.
+// The root describes where the files come from, e.g. a directory or an
+// archive, and is kept in the returned content.
+func (l *List) Walk(ctx context.Context, fsys fs.FS, root string) (*RepoContent, error) {
+	log.Debug("walking repository files", "root", root)
+	w := walker{
+		List:    l,
+		fsys:    fsys,
+		content: &RepoContent{Root: root, FS: fsys},
+		links:   make(map[string]string),
+		targets: memfs.New(),
+		skipped: make(map[string]int),
+	}
+	if err := w.walk(ctx, ".", ".", nil); err != nil {
+		return nil, err
+	}
+	if len(w.skipped) > 0 {
+		args := make([]any, 0, 2*len(w.skipped))
+		for _, attr := range markedAttrs {
+			if n := w.skipped[attr]; n > 0 {
+				args = append(args, attr, n)
+			}
+		}
+		log.Info("skipped paths marked in "+gitattr.FileName, args...)
 	}
 
-	if err := filepath.WalkDir(repoDir, func(path string, d fs.DirEntry, err error) error {
+	content := w.content
+	if len(w.links) > 0 {
+		content.FS = &linkFS{FS: content.FS, links: w.links}
+	}
+	if w.recorded > 0 {
+		content.FS = memfs.Overlay(content.FS, w.targets)
+	}
+	log.Debug("walk completed", "files", len(content.Files))
+	return content, nil
+}
+
+// walker holds the state of a Walk.
+type walker struct {
+	*List
+	fsys     fs.FS
+	content  *RepoContent
+	links    map[string]string // followed links by name, with their resolved targets
+	targets  *memfs.FS         // recorded links, with their targets as content
+	recorded int
+	attrs    gitattr.Attributes
+	skipped  map[string]int // files and directories skipped by the attribute marking them
+}
+
+// walk lists the files under dir as if dir was named name, which differs
+// inside followed directory links. The targets of the directory links
+// being followed are kept in chain to detect cycles.
+func (w *walker) walk(ctx context.Context, dir, name string, chain []string) error {
+	w.loadAttrs(dir)
+	return fs.WalkDir(w.fsys, dir, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if path == repoDir {
-			return nil
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 
-		relPath := strings.TrimPrefix(path, repoDir+"/")
-		isDotFile := l.dotIgnore && strings.HasPrefix(filepath.Base(path), ".")
+		if p == dir {
+			return nil
+		}
+		relPath := p
+		if dir != "." {
+			relPath = path.Join(name, strings.TrimPrefix(p, dir+"/"))
+		}
 
-		if d.IsDir() {
-			if isDotFile {
-				log.Debug("skipping dot directory", "dir", relPath)
-				return filepath.SkipDir
-			}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return w.symlink(ctx, p, relPath, chain)
+		}
 
-			if !l.shouldIncludePath(relPath, true) {
-				log.Debug("skipping filtered directory", "dir", relPath)
-				return filepath.SkipDir
+		if include, err := w.include(relPath, d.IsDir()); !include {
+			if err == nil && d.IsDir() {
+				if w.marked(p, relPath, true) {
+					return fs.SkipDir
+				}
+				w.loadAttrs(p)
 			}
-
+			return err
+		}
+		if w.marked(p, relPath, false) {
 			return nil
 		}
 
-		if isDotFile {
-			log.Debug("skipping dot file", "file", relPath)
-			return nil
+		w.content.Files = append(w.content.Files, relPath)
+		return nil
+	})
+}
+
+// loadAttrs reads the .gitattributes file of a walked directory.
+// A file that cannot be read is ignored with a warning.
+func (w *walker) loadAttrs(dir string) {
+	if err := w.attrs.Load(w.fsys, dir); err != nil {
+		log.Warn("ignoring "+gitattr.FileName, "dir", dir, "error", err)
+	}
+}
+
+// marked reports whether .gitattributes marks the file at p with one of the
+// attributes whose files are skipped, and counts it for that attribute.
+// Like git archive, directories are only checked for export-ignore.
+// Kept files are tagged with the kind of code they are marked as.
+func (w *walker) marked(p, relPath string, isDir bool) bool {
+	for _, attr := range markedAttrs {
+		if isDir && attr != gitattr.ExportIgnore {
+			continue
 		}
+		if !w.attrs.IsSet(p, attr) {
+			continue
+		}
+		if !slices.Contains(w.keepMarked, attr) {
+			log.Debug("skipping path marked in "+gitattr.FileName, "path", relPath, "attribute", attr)
+			w.skipped[attr]++
+			return true
+		}
+		if tag, ok := markedTags[attr]; ok && !isDir {
+			w.content.Tag(relPath, tag)
+		}
+	}
+	return false
+}
 
-		if !l.shouldIncludePath(relPath, false) {
-			log.Debug("skipping filtered file", "file", relPath)
+// symlink applies the symlink policy to the link at p, listed as relPath.
+// Links that cannot be followed are skipped with a warning.
+func (w *walker) symlink(ctx context.Context, p, relPath string, chain []string) error {
+	switch w.symlinks {
+	case SymlinksSkip:
+		log.Debug("skipping symlink", "file", relPath)
+		return nil
+	case SymlinksRecord, "":
+		target, err := fs.ReadLink(w.fsys, p)
+		if err != nil {
+			log.Warn("skipping unreadable symlink", "file", relPath, "error", err)
 			return nil
 		}
+		if include, _ := w.include(relPath, false); include && !w.marked(p, relPath, false) {
+			w.targets.Add(relPath, int64(len(target)), fs.ModeSymlink|0777, time.Time{}, memfs.Bytes([]byte(target)))
+			w.recorded++
+			w.content.Files = append(w.content.Files, relPath)
+		}
+		return nil
+	}
 
-		content.Files = append(content.Files, relPath)
+	target, err := resolveLink(w.fsys, p)
+	if err != nil {
+		log.Warn("skipping symlink", "file", relPath, "error", err)
+		return nil
+	}
+	info, err := fs.Stat(w.fsys, target)
+	if err != nil {
+		log.Warn("skipping symlink", "file", relPath, "error", err)
+		return nil
+	}
+
+	if !info.IsDir() {
+		if include, _ := w.include(relPath, false); include && !w.marked(p, relPath, false) {
+			w.links[relPath] = target
+			w.content.Files = append(w.content.Files, relPath)
+		}
 		return nil
-	}); err != nil {
-		return nil, err
 	}
 
-	log.Debug("directory walk completed", "files", len(content.Files))
-	return &content, nil
+	// A link to a directory containing it, or to a directory whose link is
+	// already being followed, would be walked forever.
+	if target == "." || strings.HasPrefix(p, target+"/") || slices.Contains(chain, target) {
+		log.Warn("skipping symlink cycle", "file", relPath, "target", target)
+		return nil
+	}
+	// Returning fs.SkipDir for the link would skip the rest of its parent,
+	// since WalkDir does not see it as a directory.
+	if _, err := w.include(relPath, true); err != nil {
+		return nil
+	}
+	log.Debug("following directory symlink", "dir", relPath, "target", target)
+	w.links[relPath] = target
+	return w.walk(ctx, target, relPath, append(chain, target))
 }
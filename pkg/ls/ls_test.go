@@ -0,0 +1,152 @@
+package ls
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/i-zaitsev/gitcat/pkg/gitclone"
+	"github.com/i-zaitsev/gitcat/pkg/internal/gittest"
+)
+
+// testRepo is a small repository tree.
+var testRepo = fstest.MapFS{
+	"README.md":           {Data: []byte("readme")},
+	".gitignore":          {Data: []byte("bin/")},
+	".github/ci.yaml":     {Data: []byte("ci")},
+	"cmd/app/main.go":     {Data: []byte("package main")},
+	"pkg/api/api.go":      {Data: []byte("package api")},
+	"pkg/api/api_test.go": {Data: []byte("package api")},
+	"pkg/db/db.go":        {Data: []byte("package db")},
+	"pkg/db/testdata/x":   {Data: []byte("x")},
+	"docs/guide.md":       {Data: []byte("guide")},
+}
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		name string
+		list *List
+		want []string
+	}{
+		{
+			name: "all",
+			list: NewList(),
+			want: []string{
+				".github/ci.yaml", ".gitignore", "README.md", "cmd/app/main.go", "docs/guide.md",
+				"pkg/api/api.go", "pkg/api/api_test.go", "pkg/db/db.go", "pkg/db/testdata/x",
+			},
+		},
+		{
+			name: "ignore dot files",
+			list: NewList().IgnoreDotFiles(),
+			want: []string{
+				"README.md", "cmd/app/main.go", "docs/guide.md",
+				"pkg/api/api.go", "pkg/api/api_test.go", "pkg/db/db.go", "pkg/db/testdata/x",
+			},
+		},
+		{
+			name: "include paths",
+			list: NewList().WithPaths("pkg/api", "docs/guide.md"),
+			want: []string{"docs/guide.md", "pkg/api/api.go", "pkg/api/api_test.go"},
+		},
+		{
+			name: "exclude paths",
+			list: NewList().IgnoreDotFiles().ExcludePaths("pkg/db/testdata", "docs", "cmd"),
+			want: []string{"README.md", "pkg/api/api.go", "pkg/api/api_test.go", "pkg/db/db.go"},
+		},
+		{
+			name: "exclude within include",
+			list: NewList().WithPaths("pkg").ExcludePaths("pkg/db/testdata", "pkg/api/api_test.go"),
+			want: []string{"pkg/api/api.go", "pkg/db/db.go"},
+		},
+		{
+			name: "prefix is not a parent",
+			list: NewList().WithPaths("pkg/d", "cmd/app/main"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := tt.list.Walk(context.Background(), testRepo, "repo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if content.Root != "repo" {
+				t.Errorf("Root = %q, want %q", content.Root, "repo")
+			}
+			if !slices.Equal(content.Files, tt.want) {
+				t.Errorf("Files = %q, want %q", content.Files, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewList().Walk(ctx, testRepo, "repo"); err == nil {
+		t.Error("Walk with a canceled context succeeded")
+	}
+}
+
+func TestLocalRepoSubmodules(t *testing.T) {
+	lib := gittest.NewRepo(t, map[string]string{"lib.go": "package lib"})
+	dir := gittest.NewRepo(t, map[string]string{"main.go": "package main"})
+	gittest.Git(t, dir, "-c", "protocol.file.allow=always", "submodule", "add", "-q", lib, "third_party/lib")
+	gittest.Git(t, dir, "commit", "-q", "-m", "add lib")
+	commit := gittest.Git(t, filepath.Join(dir, "third_party/lib"), "rev-parse", "HEAD")
+
+	tests := []struct {
+		mode gitclone.Submodules
+		want []string
+	}{
+		{"", []string{"main.go", "third_party/lib/lib.go"}},
+		{gitclone.SubmodulesNone, []string{"main.go"}},
+		{gitclone.SubmodulesShallow, []string{"main.go", "third_party/lib/lib.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			list := NewList().IgnoreDotFiles().CloneOptions(gitclone.Options{Submodules: tt.mode})
+			content, err := list.LocalRepo(context.Background(), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(content.Files, tt.want) {
+				t.Fatalf("LocalRepo() = %q, want %q", content.Files, tt.want)
+			}
+			if len(tt.want) == 2 {
+				if got := content.Attrs["third_party/lib/lib.go"].Submodule; got != "third_party/lib@"+commit {
+					t.Errorf("submodule of lib.go = %q, want %q", got, "third_party/lib@"+commit)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalRepoGitDir(t *testing.T) {
+	repo := gittest.NewRepo(t, map[string]string{"main.go": "package main", "pkg/a.go": "package pkg"})
+	plain := t.TempDir()
+	gittest.WriteFiles(t, plain, map[string]string{"main.go": "package main"})
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"repository", repo, repo},
+		{"subdirectory", filepath.Join(repo, "pkg"), filepath.Join(repo, "pkg")},
+		{"plain directory", plain, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := NewList().LocalRepo(context.Background(), tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if content.GitDir != tt.want {
+				t.Errorf("GitDir = %q, want %q", content.GitDir, tt.want)
+			}
+		})
+	}
+}
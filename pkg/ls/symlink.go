@@ -0,0 +1,127 @@
+package ls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Symlinks decides how symbolic links are listed and implements flag.Value interface.
+// Links are recorded unless following them is asked for, so that the files
+// listed never depend on where the links point to.
+type Symlinks string
+
+const (
+	SymlinksSkip   Symlinks = "skip"   // leave links out
+	SymlinksRecord Symlinks = "record" // list links as files whose content is their target
+	SymlinksFollow Symlinks = "follow" // list the targets of links that stay within the root
+)
+
+// String returns the string representation of the policy.
+func (s *Symlinks) String() string {
+	if s == nil || *s == "" {
+		return string(SymlinksRecord)
+	}
+	return string(*s)
+}
+
+// Set validates and sets the policy.
+func (s *Symlinks) Set(value string) error {
+	switch Symlinks(value) {
+	case SymlinksSkip, SymlinksRecord, SymlinksFollow:
+		*s = Symlinks(value)
+		return nil
+	default:
+		return fmt.Errorf("invalid symlinks policy %q: must be one of: skip, record, follow", value)
+	}
+}
+
+// maxLinkHops bounds the links followed to resolve a path, like ELOOP does on Linux.
+const maxLinkHops = 40
+
+var (
+	errLinkEscapes = errors.New("link target is outside the root")
+	errLinkLoop    = errors.New("too many levels of symbolic links")
+)
+
+// resolveLink returns the path in fsys that name refers to once every
+// symbolic link along it is followed. Links whose targets leave the root
+// of fsys, including absolute ones, are rejected.
+func resolveLink(fsys fs.FS, name string) (string, error) {
+	resolved, rest := ".", strings.Split(name, "/")
+	for hops := 0; len(rest) > 0; {
+		elem := rest[0]
+		rest = rest[1:]
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if resolved == "." {
+				return "", errLinkEscapes
+			}
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, elem)
+		info, err := fs.Lstat(fsys, next)
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if hops++; hops > maxLinkHops {
+			return "", errLinkLoop
+		}
+		target, err := fs.ReadLink(fsys, next)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			return "", errLinkEscapes
+		}
+		rest = append(strings.Split(target, "/"), rest...)
+	}
+	return resolved, nil
+}
+
+// linkFS serves followed links from their targets: a name at or under a
+// link is opened at the same place under the target.
+type linkFS struct {
+	fs.FS
+	links map[string]string
+}
+
+// resolve maps a name to its path in the underlying file system.
+func (l *linkFS) resolve(name string) string {
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		if target, ok := l.links[dir]; ok {
+			return path.Join(target, strings.TrimPrefix(name, dir))
+		}
+	}
+	return name
+}
+
+// Open implements fs.FS.
+func (l *linkFS) Open(name string) (fs.File, error) {
+	return l.FS.Open(l.resolve(name))
+}
+
+// Stat implements fs.StatFS.
+func (l *linkFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(l.FS, l.resolve(name))
+}
+
+// Close implements io.Closer.
+func (l *linkFS) Close() error {
+	if c, ok := l.FS.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package ls
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"slices"
+	"strconv"
+	"testing"
+	"testing/fstest"
+)
+
+// link returns a symbolic link to target for a MapFS.
+func link(target string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(target), Mode: fs.ModeSymlink | 0777}
+}
+
+func TestResolveLink(t *testing.T) {
+	fsys := fstest.MapFS{
+		"README.md":        {Data: []byte("readme")},
+		"docs/guide.md":    {Data: []byte("guide")},
+		"docs/readme":      link("../README.md"),
+		"docs/self":        link("."),
+		"current":          link("docs"),
+		"current-guide":    link("current/guide.md"),
+		"via-parent":       link("docs/../README.md"),
+		"up":               link(".."),
+		"escape":           link("../outside"),
+		"deep/escape":      link("../../outside"),
+		"absolute":         link("/etc/passwd"),
+		"dangling":         link("missing.md"),
+		"loop-a":           link("loop-b"),
+		"loop-b":           link("loop-a"),
+		"docs/sub/through": link("../../current/guide.md"),
+	}
+	// A chain of links one hop longer than allowed, and one that is just short enough.
+	for i := range maxLinkHops + 1 {
+		fsys["hop"+strconv.Itoa(i)] = link("hop" + strconv.Itoa(i+1))
+	}
+	fsys["hop"+strconv.Itoa(maxLinkHops+1)] = &fstest.MapFile{Data: []byte("end")}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "README.md", want: "README.md"},
+		{name: "docs/readme", want: "README.md"},
+		{name: "docs/self", want: "docs"},
+		{name: "current", want: "docs"},
+		{name: "current/guide.md", want: "docs/guide.md"},
+		{name: "current-guide", want: "docs/guide.md"},
+		{name: "via-parent", want: "README.md"},
+		{name: "docs/sub/through", want: "docs/guide.md"},
+		{name: "hop1", want: "hop" + strconv.Itoa(maxLinkHops+1)},
+		{name: "up", wantErr: errLinkEscapes},
+		{name: "escape", wantErr: errLinkEscapes},
+		{name: "deep/escape", wantErr: errLinkEscapes},
+		{name: "absolute", wantErr: errLinkEscapes},
+		{name: "dangling", wantErr: fs.ErrNotExist},
+		{name: "loop-a", wantErr: errLinkLoop},
+		{name: "hop0", wantErr: errLinkLoop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveLink(fsys, tt.name)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("resolveLink(%q) = %q, %v, want error %v", tt.name, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolveLink(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkSymlinks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"README.md":     {Data: []byte("readme")},
+		"docs/guide.md": {Data: []byte("guide")},
+		"guide.md":      link("docs/guide.md"),
+		"manual":        link("docs"),
+		"outside":       link("../secret"),
+		"docs/loop":     link(".."),
+	}
+
+	tests := []struct {
+		policy Symlinks
+		want   []string
+		read   map[string]string
+	}{
+		{
+			policy: "",
+			want:   []string{"README.md", "docs/guide.md", "docs/loop", "guide.md", "manual", "outside"},
+			read:   map[string]string{"guide.md": "docs/guide.md", "outside": "../secret"},
+		},
+		{
+			policy: SymlinksRecord,
+			want:   []string{"README.md", "docs/guide.md", "docs/loop", "guide.md", "manual", "outside"},
+			read:   map[string]string{"manual": "docs"},
+		},
+		{
+			policy: SymlinksFollow,
+			want:   []string{"README.md", "docs/guide.md", "guide.md", "manual/guide.md"},
+			read:   map[string]string{"guide.md": "guide", "manual/guide.md": "guide"},
+		},
+		{
+			policy: SymlinksSkip,
+			want:   []string{"README.md", "docs/guide.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			content, err := NewList().WithSymlinks(tt.policy).Walk(context.Background(), fsys, "repo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(content.Files)
+			if !slices.Equal(content.Files, tt.want) {
+				t.Fatalf("Walk() = %q, want %q", content.Files, tt.want)
+			}
+			for name, want := range tt.read {
+				data, err := fs.ReadFile(content.FS, name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != want {
+					t.Errorf("ReadFile(%q) = %q, want %q", name, data, want)
+				}
+			}
+		})
+	}
+}
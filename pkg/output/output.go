@@ -1,6 +1,7 @@
 package output
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -38,25 +39,43 @@ func (f *Format) Set(value string) error {
 	}
 }
 
-func ToText(repo *ls.RepoContent, headLines int) string {
+func ToText(ctx context.Context, repo *ls.RepoContent, lines files.Lines) (string, error) {
 	var buf strings.Builder
+	progress := files.NewProgress(len(repo.Files))
+	defer progress.Done()
 	for _, ext := range files.DiscoverExt(repo) {
 		extRepo := files.MatchExt(repo, ext)
-		buf.WriteString(files.Cat(headLines, extRepo.Files...) + "\n")
+		content, err := files.Cat(ctx, repo, lines, extRepo.Files...)
+		if err != nil {
+			return "", err
+		}
+		progress.Add(len(extRepo.Files), int64(len(content)))
+		buf.WriteString(content + "\n")
 	}
-	return buf.String()
+	return buf.String(), nil
 }
 
-func ToJSONL(repo *ls.RepoContent, headLines int) (string, error) {
+func ToJSONL(ctx context.Context, repo *ls.RepoContent, lines files.Lines) (string, error) {
 	var buf strings.Builder
+	progress := files.NewProgress(len(repo.Files))
+	defer progress.Done()
 
 	for _, ext := range files.DiscoverExt(repo) {
 		extRepo := files.MatchExt(repo, ext)
 		for _, filename := range extRepo.Files {
-			entry := outputEntry{
-				File:    filename,
-				Ext:     ext,
-				Content: files.Cat(headLines, filename),
+			text, err := files.Cat(ctx, repo, lines, filename)
+			if err != nil {
+				return "", err
+			}
+			progress.Add(1, int64(len(text)))
+			attrs := repo.Attr(filename)
+			entry := Entry{
+				File:      filename,
+				Ext:       ext,
+				Status:    attrs.Status,
+				Submodule: attrs.Submodule,
+				Tags:      attrs.Tags,
+				Content:   text,
 			}
 			content, err := json.Marshal(entry)
 			if err != nil {
@@ -73,37 +92,82 @@ func ToJSONL(repo *ls.RepoContent, headLines int) (string, error) {
 
 // ToMarkdown formats repository content as Markdown with code blocks.
 // Uses the same file iteration as JSONL but outputs a Markdown format.
-func ToMarkdown(repo *ls.RepoContent, headLines int) string {
+func ToMarkdown(ctx context.Context, repo *ls.RepoContent, lines files.Lines) (string, error) {
 	var buf strings.Builder
+	progress := files.NewProgress(len(repo.Files))
+	defer progress.Done()
 
 	for _, ext := range files.DiscoverExt(repo) {
 		extRepo := files.MatchExt(repo, ext)
 		for _, filename := range extRepo.Files {
-			content := files.Cat(headLines, filename)
+			content, err := files.Cat(ctx, repo, lines, filename)
+			if err != nil {
+				return "", err
+			}
+			progress.Add(1, int64(len(content)))
 
 			buf.WriteString("## ")
 			buf.WriteString(filename)
 			buf.WriteString("\n")
 			buf.WriteString("*Extension: ")
 			buf.WriteString(ext)
-			buf.WriteString("*\n\n")
-			buf.WriteString("```")
+			buf.WriteString("*\n")
+			attrs := repo.Attr(filename)
+			if attrs.Status != "" {
+				buf.WriteString("*Status: ")
+				buf.WriteString(attrs.Status)
+				buf.WriteString("*\n")
+			}
+			if attrs.Submodule != "" {
+				buf.WriteString("*Submodule: ")
+				buf.WriteString(attrs.Submodule)
+				buf.WriteString("*\n")
+			}
+			if len(attrs.Tags) > 0 {
+				buf.WriteString("*Tags: ")
+				buf.WriteString(strings.Join(attrs.Tags, ","))
+				buf.WriteString("*\n")
+			}
+			buf.WriteString("\n")
+			f := fence(content)
+			buf.WriteString(f)
 			buf.WriteString(strings.TrimPrefix(ext, "."))
 			buf.WriteString("\n")
 			buf.WriteString(content)
-			if !strings.HasSuffix(content, "\n") {
+			if content != "" && !strings.HasSuffix(content, "\n") {
 				buf.WriteString("\n")
 			}
-			buf.WriteString("```\n\n")
+			buf.WriteString(f)
+			buf.WriteString("\n\n")
 			buf.WriteString("---\n\n")
 		}
 	}
 
-	return buf.String()
+	return buf.String(), nil
+}
+
+// fence returns the code fence of a Markdown block holding content: a run
+// of backticks longer than any in content, and at least three long, so that
+// the fences and separators inside the content do not end the block.
+func fence(content string) string {
+	longest, run := 0, 0
+	for i := 0; i < len(content); i++ {
+		if content[i] != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return strings.Repeat("`", max(3, longest+1))
 }
 
-type outputEntry struct {
-	File    string `json:"file"`
-	Ext     string `json:"ext"`
-	Content string `json:"content"`
+// Entry is a single file record of the JSONL output.
+type Entry struct {
+	File      string   `json:"file"`
+	Ext       string   `json:"ext"`
+	Status    string   `json:"status,omitempty"`    // changes of a local file against HEAD, e.g. "modified"
+	Submodule string   `json:"submodule,omitempty"` // path and commit of the submodule holding the file
+	Tags      []string `json:"tags,omitempty"`      // kinds of code, e.g. "generated" or "vendored"
+	Content   string   `json:"content"`
 }
@@ -0,0 +1,123 @@
+package output
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/i-zaitsev/gitcat/pkg/files"
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+// roundTripFiles holds contents that are hard to delimit, keyed by file name,
+// and the contents expected back: files are always read with a final newline.
+var roundTripFiles = []struct {
+	name, data, want string
+}{
+	{"main.go", "package main\n", "package main\n"},
+	{"README.md", "# Title\n\n```go\nx := 1\n```\n\n---\n\nmore text\n", "# Title\n\n```go\nx := 1\n```\n\n---\n\nmore text\n"},
+	{"docs/fences.md", "````\n```\n````\n\n---\n\n## not a header\n", "````\n```\n````\n\n---\n\n## not a header\n"},
+	{"docs/inline.md", "use `code` and ``` inline", "use `code` and ``` inline\n"},
+	{"notes.txt", "no trailing newline", "no trailing newline\n"},
+	{"empty.txt", "", ""},
+	{"blank.txt", "\n\n", "\n\n"},
+	{"sep.txt", "```\n\n---\n", "```\n\n---\n"},
+}
+
+// roundTripRepo returns the repository holding roundTripFiles, with metadata.
+func roundTripRepo() *ls.RepoContent {
+	fsys := fstest.MapFS{}
+	var names []string
+	for _, f := range roundTripFiles {
+		fsys[f.name] = &fstest.MapFile{Data: []byte(f.data)}
+		names = append(names, f.name)
+	}
+	repo := &ls.RepoContent{FS: fsys, Files: names}
+	repo.Tag("main.go", ls.TagGenerated)
+	repo.Tag("main.go", ls.TagVendored)
+	return repo
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(context.Context, *ls.RepoContent, files.Lines) (string, error)
+		parse func(string) ([]Entry, error)
+	}{
+		{"jsonl", ToJSONL, func(s string) ([]Entry, error) { return ParseJSONL(strings.NewReader(s)) }},
+		{"md", ToMarkdown, func(s string) ([]Entry, error) { return ParseMarkdown(strings.NewReader(s)) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.write(context.Background(), roundTripRepo(), files.Lines{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := tt.parse(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(roundTripFiles) {
+				t.Fatalf("parsed %d entries, want %d:\n%s", len(entries), len(roundTripFiles), out)
+			}
+			got := make(map[string]Entry, len(entries))
+			for _, e := range entries {
+				got[e.File] = e
+			}
+			for _, f := range roundTripFiles {
+				e, ok := got[f.name]
+				if !ok {
+					t.Errorf("no entry for %s", f.name)
+					continue
+				}
+				if e.Content != f.want {
+					t.Errorf("content of %s = %q, want %q", f.name, e.Content, f.want)
+				}
+			}
+			if tags := got["main.go"].Tags; !slices.Equal(tags, []string{ls.TagGenerated, ls.TagVendored}) {
+				t.Errorf("tags of main.go = %q", tags)
+			}
+			if ext := got["README.md"].Ext; ext != ".md" {
+				t.Errorf("ext of README.md = %q, want .md", ext)
+			}
+		})
+	}
+}
+
+func TestFence(t *testing.T) {
+	tests := []struct{ content, want string }{
+		{"", "```"},
+		{"no backticks", "```"},
+		{"`x` and ``y``", "```"},
+		{"```go\n```", "````"},
+		{"a ````` b", "``````"},
+	}
+	for _, tt := range tests {
+		if got := fence(tt.content); got != tt.want {
+			t.Errorf("fence(%q) = %s, want %s", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestParseMarkdownInvalid(t *testing.T) {
+	tests := []struct{ name, in string }{
+		{"no header", "## a.go\n```go\nx\n```\n"},
+		{"unterminated", "## a.go\n*Extension: .go*\n\n```go\nx\n"},
+		{"shorter closing fence", "## a.go\n*Extension: .go*\n\n````go\nx\n```\n\n---\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if entries, err := ParseMarkdown(strings.NewReader(tt.in)); err == nil {
+				t.Errorf("ParseMarkdown() = %+v, want an error", entries)
+			}
+		})
+	}
+}
+
+func TestParseJSONLInvalid(t *testing.T) {
+	if entries, err := ParseJSONL(strings.NewReader("{\"file\":\"a\"}\n{broken\n")); err == nil {
+		t.Errorf("ParseJSONL() = %+v, want an error", entries)
+	}
+}
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseJSONL reads entries previously written by ToJSONL.
+func ParseJSONL(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	dec := json.NewDecoder(r)
+	for {
+		var entry Entry
+		if err := dec.Decode(&entry); errors.Is(err, io.EOF) {
+			return entries, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// ParseMarkdown reads entries previously written by ToMarkdown.
+// The header of an entry holds one *Key: value* line per metadata field.
+// A code block ends at a closing fence of the same length as its opening
+// one, which is longer than any run of backticks in the file contents.
+func ParseMarkdown(r io.Reader) ([]Entry, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for i := 0; i < len(lines); i++ {
+		name, ok := strings.CutPrefix(lines[i], "## ")
+		if !ok {
+			continue
+		}
+		entry := Entry{File: name}
+		j := i + 1
+		for ; j < len(lines) && strings.HasPrefix(lines[j], "*") && strings.HasSuffix(lines[j], "*"); j++ {
+			key, value, _ := strings.Cut(strings.Trim(lines[j], "*"), ": ")
+			switch key {
+			case "Extension":
+				entry.Ext = value
+			case "Status":
+				entry.Status = value
+			case "Submodule":
+				entry.Submodule = value
+			case "Tags":
+				entry.Tags = strings.Split(value, ",")
+			}
+		}
+		if j == i+1 || j+1 >= len(lines) || lines[j] != "" || !strings.HasPrefix(lines[j+1], "```") {
+			return nil, fmt.Errorf("line %d: malformed entry header for %s", i+1, name)
+		}
+		open := lines[j+1]
+		fence := open[:len(open)-len(strings.TrimLeft(open, "`"))]
+
+		var content strings.Builder
+		end := -1
+		for j += 2; j < len(lines); j++ {
+			if lines[j] == fence {
+				end = j
+				break
+			}
+			content.WriteString(lines[j])
+			content.WriteString("\n")
+		}
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unterminated code block for %s", i+1, name)
+		}
+		entry.Content = content.String()
+		entries = append(entries, entry)
+		i = end
+	}
+	return entries, nil
+}
@@ -0,0 +1,62 @@
+package output
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+type treeNode struct {
+	name     string
+	children map[string]*treeNode
+}
+
+// ToTree renders the repository files as an indented directory tree.
+func ToTree(repo *ls.RepoContent) string {
+	root := &treeNode{children: make(map[string]*treeNode)}
+	for _, file := range repo.Files {
+		node := root
+		for _, part := range strings.Split(file, "/") {
+			child, ok := node.children[part]
+			if !ok {
+				child = &treeNode{name: part, children: make(map[string]*treeNode)}
+				node.children[part] = child
+			}
+			node = child
+		}
+	}
+
+	var buf strings.Builder
+	buf.WriteString(".\n")
+	writeTree(&buf, root, "")
+	return buf.String()
+}
+
+// writeTree writes the children of node, directories first, then files, each sorted by name.
+func writeTree(buf *strings.Builder, node *treeNode, prefix string) {
+	children := make([]*treeNode, 0, len(node.children))
+	for _, child := range node.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		iDir, jDir := len(children[i].children) > 0, len(children[j].children) > 0
+		if iDir != jDir {
+			return iDir
+		}
+		return children[i].name < children[j].name
+	})
+
+	for i, child := range children {
+		branch, indent := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		buf.WriteString(prefix + branch + child.name)
+		if len(child.children) > 0 {
+			buf.WriteString("/")
+		}
+		buf.WriteString("\n")
+		writeTree(buf, child, prefix+indent)
+	}
+}
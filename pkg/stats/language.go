@@ -0,0 +1,86 @@
+package stats
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// language describes how comments are written in a family of source files.
+type language struct {
+	name       string
+	line       []string // line comment prefixes
+	blockStart string   // block comment opening delimiter
+	blockEnd   string   // block comment closing delimiter
+}
+
+var (
+	cLike    = []string{"//"}
+	hashLike = []string{"#"}
+)
+
+var languagesByExt = map[string]language{
+	".go":    {name: "Go", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".c":     {name: "C", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".h":     {name: "C", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".cc":    {name: "C++", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".cpp":   {name: "C++", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".hpp":   {name: "C++", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".cs":    {name: "C#", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".java":  {name: "Java", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".kt":    {name: "Kotlin", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".scala": {name: "Scala", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".swift": {name: "Swift", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".rs":    {name: "Rust", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".js":    {name: "JavaScript", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".jsx":   {name: "JavaScript", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".mjs":   {name: "JavaScript", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".ts":    {name: "TypeScript", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".tsx":   {name: "TypeScript", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".proto": {name: "Protocol Buffers", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".css":   {name: "CSS", blockStart: "/*", blockEnd: "*/"},
+	".scss":  {name: "SCSS", line: cLike, blockStart: "/*", blockEnd: "*/"},
+	".py":    {name: "Python", line: hashLike},
+	".rb":    {name: "Ruby", line: hashLike},
+	".pl":    {name: "Perl", line: hashLike},
+	".sh":    {name: "Shell", line: hashLike},
+	".bash":  {name: "Shell", line: hashLike},
+	".zsh":   {name: "Shell", line: hashLike},
+	".r":     {name: "R", line: hashLike},
+	".yaml":  {name: "YAML", line: hashLike},
+	".yml":   {name: "YAML", line: hashLike},
+	".toml":  {name: "TOML", line: hashLike},
+	".mk":    {name: "Makefile", line: hashLike},
+	".sql":   {name: "SQL", line: []string{"--"}, blockStart: "/*", blockEnd: "*/"},
+	".lua":   {name: "Lua", line: []string{"--"}},
+	".hs":    {name: "Haskell", line: []string{"--"}, blockStart: "{-", blockEnd: "-}"},
+	".html":  {name: "HTML", blockStart: "<!--", blockEnd: "-->"},
+	".htm":   {name: "HTML", blockStart: "<!--", blockEnd: "-->"},
+	".xml":   {name: "XML", blockStart: "<!--", blockEnd: "-->"},
+	".md":    {name: "Markdown"},
+	".txt":   {name: "Text"},
+	".json":  {name: "JSON"},
+	".mod":   {name: "Go Module", line: cLike},
+	".sum":   {name: "Go Checksums"},
+}
+
+var languagesByName = map[string]language{
+	"Makefile":   {name: "Makefile", line: hashLike},
+	"Dockerfile": {name: "Dockerfile", line: hashLike},
+}
+
+var (
+	otherLanguage  = language{name: "Other"}
+	binaryLanguage = language{name: "Binary"}
+)
+
+// detectLanguage guesses the language of a file from its name.
+func detectLanguage(path string) language {
+	base := filepath.Base(path)
+	if lang, ok := languagesByName[base]; ok {
+		return lang
+	}
+	if lang, ok := languagesByExt[strings.ToLower(filepath.Ext(base))]; ok {
+		return lang
+	}
+	return otherLanguage
+}
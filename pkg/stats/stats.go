@@ -0,0 +1,232 @@
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/i-zaitsev/gitcat/pkg/files"
+	"github.com/i-zaitsev/gitcat/pkg/internal/utils"
+	"github.com/i-zaitsev/gitcat/pkg/log"
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+// bytesPerToken is the rough ratio used to estimate LLM tokens from file size.
+const bytesPerToken = 4
+
+// Counts aggregates the size of a group of files.
+type Counts struct {
+	Files   int   `json:"files"`
+	Bytes   int64 `json:"bytes"`
+	Lines   int   `json:"lines"`
+	Blank   int   `json:"blank"`
+	Comment int   `json:"comment"`
+	Code    int   `json:"code"`
+	Tokens  int64 `json:"tokens"`
+}
+
+func (c *Counts) add(o Counts) {
+	c.Files += o.Files
+	c.Bytes += o.Bytes
+	c.Lines += o.Lines
+	c.Blank += o.Blank
+	c.Comment += o.Comment
+	c.Code += o.Code
+	c.Tokens += o.Tokens
+}
+
+// File holds the counts of a single file.
+type File struct {
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Counts
+}
+
+// Report groups file counts by extension, top-level directory and language.
+type Report struct {
+	Total       Counts             `json:"total"`
+	ByExtension map[string]*Counts `json:"by_extension"`
+	ByDirectory map[string]*Counts `json:"by_directory"`
+	ByLanguage  map[string]*Counts `json:"by_language"`
+	Largest     []File             `json:"largest"`
+}
+
+// Collect reads every file of the repository and builds the report.
+// The top largest files by size are kept in the report.
+// It stops with the context's error when ctx is done.
+func Collect(ctx context.Context, repo *ls.RepoContent, top int) (*Report, error) {
+	report := Report{
+		ByExtension: make(map[string]*Counts),
+		ByDirectory: make(map[string]*Counts),
+		ByLanguage:  make(map[string]*Counts),
+	}
+
+	progress := files.NewProgress(len(repo.Files))
+	defer progress.Done()
+
+	var all []File
+	for _, relPath := range repo.Files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		file, err := countFile(repo, relPath)
+		if err != nil {
+			log.Warn("failed to read file for stats", "file", relPath, "error", err)
+			continue
+		}
+		file.Path = relPath
+		all = append(all, file)
+		progress.Add(1, file.Bytes)
+
+		ext := filepath.Ext(relPath)
+		if ext == "" {
+			ext = "(none)"
+		}
+		dir, _, found := strings.Cut(relPath, "/")
+		if !found {
+			dir = "."
+		}
+
+		report.Total.add(file.Counts)
+		group(report.ByExtension, ext).add(file.Counts)
+		group(report.ByDirectory, dir).add(file.Counts)
+		group(report.ByLanguage, file.Language).add(file.Counts)
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Bytes > all[j].Bytes
+	})
+	report.Largest = all[:min(top, len(all))]
+
+	log.Debug("stats collected", "files", report.Total.Files, "bytes", report.Total.Bytes)
+	return &report, nil
+}
+
+func group(groups map[string]*Counts, key string) *Counts {
+	if c, ok := groups[key]; ok {
+		return c
+	}
+	c := &Counts{}
+	groups[key] = c
+	return c
+}
+
+// countFile classifies every line of the file as blank, comment or code.
+// Binary files are only counted by size.
+func countFile(repo *ls.RepoContent, path string) (File, error) {
+	f, err := repo.FS.Open(path)
+	if err != nil {
+		return File{}, err
+	}
+	defer utils.SilentClose(f)
+
+	lang := detectLanguage(path)
+	file := File{Language: lang.name, Counts: Counts{Files: 1}}
+
+	r := bufio.NewReader(f)
+	if head, _ := r.Peek(8000); bytes.IndexByte(head, 0) >= 0 {
+		n, err := io.Copy(io.Discard, r)
+		file.Language = binaryLanguage.name
+		file.Bytes = n
+		return file, err
+	}
+
+	inBlock := false
+	for {
+		line, err := r.ReadString('\n')
+		if len(line) > 0 {
+			file.Bytes += int64(len(line))
+			file.Lines++
+			inBlock = classify(&file.Counts, lang, strings.TrimSpace(line), inBlock)
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return File{}, err
+		}
+	}
+
+	file.Tokens = (file.Bytes + bytesPerToken - 1) / bytesPerToken
+	return file, nil
+}
+
+// classify counts a trimmed line and reports whether a block comment is still open after it.
+func classify(c *Counts, lang language, line string, inBlock bool) bool {
+	switch {
+	case inBlock:
+		c.Comment++
+		return !strings.Contains(line, lang.blockEnd)
+	case line == "":
+		c.Blank++
+		return false
+	case lang.blockStart != "" && strings.HasPrefix(line, lang.blockStart):
+		c.Comment++
+		rest := strings.TrimPrefix(line, lang.blockStart)
+		return !strings.Contains(rest, lang.blockEnd)
+	}
+	for _, prefix := range lang.line {
+		if strings.HasPrefix(line, prefix) {
+			c.Comment++
+			return false
+		}
+	}
+	c.Code++
+	return false
+}
+
+// WriteTable writes the report as aligned tables for terminals.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	writeGroups(tw, "extension", r.ByExtension)
+	writeGroups(tw, "directory", r.ByDirectory)
+	writeGroups(tw, "language", r.ByLanguage)
+
+	if len(r.Largest) > 0 {
+		_, _ = fmt.Fprintf(tw, "largest files\tbytes\tlines\ttokens\n")
+		for _, f := range r.Largest {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", f.Path, f.Bytes, f.Lines, f.Tokens)
+		}
+		_, _ = fmt.Fprintln(tw)
+	}
+
+	writeHeader(tw, "total")
+	writeRow(tw, "all", &r.Total)
+	return tw.Flush()
+}
+
+// writeGroups writes one table section, largest groups first.
+func writeGroups(w io.Writer, title string, groups map[string]*Counts) {
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if groups[keys[i]].Bytes != groups[keys[j]].Bytes {
+			return groups[keys[i]].Bytes > groups[keys[j]].Bytes
+		}
+		return keys[i] < keys[j]
+	})
+
+	writeHeader(w, title)
+	for _, k := range keys {
+		writeRow(w, k, groups[k])
+	}
+	_, _ = fmt.Fprintln(w)
+}
+
+func writeHeader(w io.Writer, title string) {
+	_, _ = fmt.Fprintf(w, "%s\tfiles\tbytes\tlines\tblank\tcomment\tcode\ttokens\n", title)
+}
+
+func writeRow(w io.Writer, name string, c *Counts) {
+	_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n",
+		name, c.Files, c.Bytes, c.Lines, c.Blank, c.Comment, c.Code, c.Tokens)
+}
@@ -0,0 +1,291 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/i-zaitsev/gitcat/pkg/ls"
+)
+
+// testContent lists every file of fsys.
+func testContent(fsys fstest.MapFS) *ls.RepoContent {
+	var names []string
+	for name := range fsys {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return &ls.RepoContent{Root: "repo", Files: names, FS: fsys}
+}
+
+func TestCountFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		data     string
+		wantLang string
+		want     Counts
+	}{
+		{
+			name: "code, blank and line comments", path: "main.go",
+			data:     "package main\n\n// Run starts.\nfunc Run() {}\n",
+			wantLang: "Go",
+			want:     Counts{Files: 1, Bytes: 43, Lines: 4, Blank: 1, Comment: 1, Code: 2, Tokens: 11},
+		},
+		{
+			name: "whitespace only lines are blank", path: "a.go",
+			data:     "x := 1\n  \t\n\n",
+			wantLang: "Go",
+			want:     Counts{Files: 1, Bytes: 12, Lines: 3, Blank: 2, Code: 1, Tokens: 3},
+		},
+		{
+			name: "block comment on one line", path: "a.c",
+			data:     "/* one line */\nint x;\n",
+			wantLang: "C",
+			want:     Counts{Files: 1, Bytes: 22, Lines: 2, Comment: 1, Code: 1, Tokens: 6},
+		},
+		{
+			name: "block comment across lines", path: "a.java",
+			data:     "/*\n * Doc.\n\n */\nclass A {}\n",
+			wantLang: "Java",
+			want:     Counts{Files: 1, Bytes: 27, Lines: 5, Comment: 4, Code: 1, Tokens: 7},
+		},
+		{
+			name: "indented block comment", path: "a.ts",
+			data:     "  /** doc\n   */\nlet x = 1\n",
+			wantLang: "TypeScript",
+			want:     Counts{Files: 1, Bytes: 26, Lines: 3, Comment: 2, Code: 1, Tokens: 7},
+		},
+		{
+			name: "html block comment", path: "index.html",
+			data:     "<!--\nnote\n-->\n<p>hi</p>\n",
+			wantLang: "HTML",
+			want:     Counts{Files: 1, Bytes: 24, Lines: 4, Comment: 3, Code: 1, Tokens: 6},
+		},
+		{
+			name: "hash comments", path: "run.py",
+			data:     "# comment\nx = 1 # trailing\n",
+			wantLang: "Python",
+			want:     Counts{Files: 1, Bytes: 27, Lines: 2, Comment: 1, Code: 1, Tokens: 7},
+		},
+		{
+			name: "slashes are code without line comments", path: "a.css",
+			data:     "// not a comment\n/* a comment */\n",
+			wantLang: "CSS",
+			want:     Counts{Files: 1, Bytes: 33, Lines: 2, Comment: 1, Code: 1, Tokens: 9},
+		},
+		{
+			name: "language from the file name", path: "build/Makefile",
+			data:     "# targets\nall:\n",
+			wantLang: "Makefile",
+			want:     Counts{Files: 1, Bytes: 15, Lines: 2, Comment: 1, Code: 1, Tokens: 4},
+		},
+		{
+			name: "unknown extension", path: "notes.xyz",
+			data:     "// code\n",
+			wantLang: "Other",
+			want:     Counts{Files: 1, Bytes: 8, Lines: 1, Code: 1, Tokens: 2},
+		},
+		{
+			name: "missing trailing newline", path: "a.go",
+			data:     "a\nb",
+			wantLang: "Go",
+			want:     Counts{Files: 1, Bytes: 3, Lines: 2, Code: 2, Tokens: 1},
+		},
+		{
+			name: "empty", path: "a.go",
+			wantLang: "Go",
+			want:     Counts{Files: 1},
+		},
+		{
+			name: "binary", path: "a.go",
+			data:     "abc\x00def\nghi\n",
+			wantLang: "Binary",
+			want:     Counts{Files: 1, Bytes: 12},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := testContent(fstest.MapFS{tt.path: {Data: []byte(tt.data)}})
+			got, err := countFile(repo, tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Language != tt.wantLang || got.Counts != tt.want {
+				t.Errorf("countFile(%q) = %s %+v, want %s %+v", tt.data, got.Language, got.Counts, tt.wantLang, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	repo := testContent(fstest.MapFS{
+		"main.go":        {Data: []byte("package main\n\nfunc main() {}\n")},
+		"pkg/a.go":       {Data: []byte("// Package a.\npackage a\n")},
+		"pkg/b/b.go":     {Data: []byte("package b\n")},
+		"docs/README.md": {Data: []byte("# Docs\n\nText.\n")},
+		"LICENSE":        {Data: []byte("MIT\n")},
+		"logo.png":       {Data: []byte("\x89PNG\x00\x00")},
+	})
+	repo.Files = append(repo.Files, "missing.go")
+
+	report, err := Collect(context.Background(), repo, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTotal := Counts{Files: 6, Bytes: 87, Lines: 10, Blank: 2, Comment: 1, Code: 7, Tokens: 22}
+	if report.Total != wantTotal {
+		t.Errorf("Total = %+v, want %+v", report.Total, wantTotal)
+	}
+
+	groups := []struct {
+		name   string
+		groups map[string]*Counts
+		want   map[string]Counts
+	}{
+		{"ByExtension", report.ByExtension, map[string]Counts{
+			".go":    {Files: 3, Bytes: 63, Lines: 6, Blank: 1, Comment: 1, Code: 4, Tokens: 17},
+			".md":    {Files: 1, Bytes: 14, Lines: 3, Blank: 1, Code: 2, Tokens: 4},
+			".png":   {Files: 1, Bytes: 6},
+			"(none)": {Files: 1, Bytes: 4, Lines: 1, Code: 1, Tokens: 1},
+		}},
+		{"ByDirectory", report.ByDirectory, map[string]Counts{
+			".":    {Files: 3, Bytes: 39, Lines: 4, Blank: 1, Code: 3, Tokens: 9},
+			"pkg":  {Files: 2, Bytes: 34, Lines: 3, Comment: 1, Code: 2, Tokens: 9},
+			"docs": {Files: 1, Bytes: 14, Lines: 3, Blank: 1, Code: 2, Tokens: 4},
+		}},
+		{"ByLanguage", report.ByLanguage, map[string]Counts{
+			"Go":       {Files: 3, Bytes: 63, Lines: 6, Blank: 1, Comment: 1, Code: 4, Tokens: 17},
+			"Markdown": {Files: 1, Bytes: 14, Lines: 3, Blank: 1, Code: 2, Tokens: 4},
+			"Binary":   {Files: 1, Bytes: 6},
+			"Other":    {Files: 1, Bytes: 4, Lines: 1, Code: 1, Tokens: 1},
+		}},
+	}
+	for _, g := range groups {
+		if len(g.groups) != len(g.want) {
+			t.Errorf("%s has %d groups, want %d", g.name, len(g.groups), len(g.want))
+		}
+		for key, want := range g.want {
+			if got := g.groups[key]; got == nil || *got != want {
+				t.Errorf("%s[%q] = %+v, want %+v", g.name, key, got, want)
+			}
+		}
+	}
+
+	var largest []string
+	for _, f := range report.Largest {
+		largest = append(largest, f.Path)
+	}
+	if want := []string{"main.go", "pkg/a.go", "docs/README.md"}; !slices.Equal(largest, want) {
+		t.Errorf("Largest = %q, want %q", largest, want)
+	}
+}
+
+func TestCollectTop(t *testing.T) {
+	repo := testContent(fstest.MapFS{
+		"a.txt": {Data: []byte("aaaa\n")},
+		"b.txt": {Data: []byte("bb\n")},
+		"c.txt": {Data: []byte("cc\n")},
+	})
+
+	tests := []struct {
+		top  int
+		want []string
+	}{
+		{0, nil},
+		{1, []string{"a.txt"}},
+		{2, []string{"a.txt", "b.txt"}},
+		{10, []string{"a.txt", "b.txt", "c.txt"}},
+	}
+	for _, tt := range tests {
+		report, err := Collect(context.Background(), repo, tt.top)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, f := range report.Largest {
+			got = append(got, f.Path)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Collect(top=%d).Largest = %q, want %q", tt.top, got, tt.want)
+		}
+	}
+}
+
+func TestCollectCanceled(t *testing.T) {
+	repo := testContent(fstest.MapFS{"a.go": {Data: []byte("package a\n")}})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Collect(ctx, repo, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Collect() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	repo := testContent(fstest.MapFS{
+		"main.go":    {Data: []byte("package main\n\n// main runs.\nfunc main() {}\n")},
+		"pkg/x.py":   {Data: []byte("x = 1\n")},
+		"pkg/README": {Data: []byte("Read me.\n")},
+	})
+	report, err := Collect(context.Background(), repo, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"extension  files  bytes  lines  blank  comment  code  tokens\n" +
+		".go        1      43     4      1      1        2     11\n" +
+		"(none)     1      9      1      0      0        1     3\n" +
+		".py        1      6      1      0      0        1     2\n" +
+		"\n" +
+		"directory  files  bytes  lines  blank  comment  code  tokens\n" +
+		".          1      43     4      1      1        2     11\n" +
+		"pkg        2      15     2      0      0        2     5\n" +
+		"\n" +
+		"language  files  bytes  lines  blank  comment  code  tokens\n" +
+		"Go        1      43     4      1      1        2     11\n" +
+		"Other     1      9      1      0      0        1     3\n" +
+		"Python    1      6      1      0      0        1     2\n" +
+		"\n" +
+		"largest files  bytes  lines  tokens\n" +
+		"main.go        43     4      11\n" +
+		"pkg/README     9      1      3\n" +
+		"\n" +
+		"total  files  bytes  lines  blank  comment  code  tokens\n" +
+		"all    3      58     6      1      1        4     16\n"
+	if buf.String() != want {
+		t.Errorf("WriteTable() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	repo := testContent(fstest.MapFS{
+		"main.go": {Data: []byte("package main\n\n// main runs.\nfunc main() {}\n")},
+	})
+	report, err := Collect(context.Background(), repo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"total":{"files":1,"bytes":43,"lines":4,"blank":1,"comment":1,"code":2,"tokens":11},` +
+		`"by_extension":{".go":{"files":1,"bytes":43,"lines":4,"blank":1,"comment":1,"code":2,"tokens":11}},` +
+		`"by_directory":{".":{"files":1,"bytes":43,"lines":4,"blank":1,"comment":1,"code":2,"tokens":11}},` +
+		`"by_language":{"Go":{"files":1,"bytes":43,"lines":4,"blank":1,"comment":1,"code":2,"tokens":11}},` +
+		`"largest":[{"path":"main.go","language":"Go","files":1,"bytes":43,"lines":4,"blank":1,"comment":1,"code":2,"tokens":11}]}`
+	if string(data) != want {
+		t.Errorf("json.Marshal(report) =\n%s\nwant\n%s", data, want)
+	}
+}
@@ -14,10 +14,14 @@ const (
 	blue   = "\033[34m"
 )
 
-var fmt func(code, s string) string
+var (
+	fmt        func(code, s string) string
+	isTerminal bool
+)
 
 func init() {
-	if term.IsTerminal(int(os.Stderr.Fd())) {
+	isTerminal = term.IsTerminal(int(os.Stderr.Fd()))
+	if isTerminal {
 		fmt = func(code, s string) string {
 			return code + s + reset
 		}
@@ -28,6 +32,11 @@ func init() {
 	}
 }
 
+// IsTerminal reports whether stderr is a terminal.
+func IsTerminal() bool {
+	return isTerminal
+}
+
 // Red returns text in red color.
 func Red(s string) string {
 	return fmt(red, s)